@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+func runAdminCommand(args []string) error {
+	switch args[0] {
+	case "keys":
+		return runKeysCommand(args[1:])
+	case "clients":
+		return runClientsCommand(args[1:])
+	case "roles":
+		return runRolesCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, `Usage:
+  auth-service                                    start the HTTP server
+  auth-service keys list                          list signing keys of JWT_KEYS_DIR
+  auth-service keys rotate [-alg ES256] [-if-older-than 720h]
+                                                  generate a new active signing key
+  auth-service keys prune                         remove keys past their grace period
+  auth-service clients list                       list registered OAuth clients
+  auth-service clients create -id ID [-name NAME] [-grants authorization_code,client_credentials]
+                              [-scopes a,b] [-audiences a,b] [-redirect-uris URI,...] [-access-ttl 15m]
+                              [-refresh-ttl 720h] [-public]
+                                                  register a client and print its secret once
+  auth-service roles list                         list roles and their permissions
+  auth-service roles assign -user ID -role NAME   assign a role, e.g. the first admin`)
+}
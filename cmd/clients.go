@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"auth-service/config"
+	"auth-service/database"
+	"auth-service/internal/auth"
+	"auth-service/internal/repository"
+	"auth-service/models"
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+func runClientsCommand(args []string) error {
+	if len(args) == 0 {
+		printUsage()
+		return fmt.Errorf("clients: missing subcommand")
+	}
+
+	ctx := context.Background()
+	conn := database.InitPostgres(ctx)
+	defer conn.Close()
+
+	repo := repository.NewRepository(conn)
+
+	switch args[0] {
+	case "create":
+		return createClient(ctx, repo, args[1:])
+	case "list":
+		return listClients(ctx, repo)
+	default:
+		printUsage()
+		return fmt.Errorf("clients: unknown subcommand %q", args[0])
+	}
+}
+
+func createClient(ctx context.Context, repo repository.RepositoryI, args []string) error {
+	fs := flag.NewFlagSet("clients create", flag.ContinueOnError)
+	id := fs.String("id", "", "client_id")
+	name := fs.String("name", "", "human readable name")
+	grants := fs.String("grants", models.GrantAuthorizationCode, "comma separated grant types: authorization_code, client_credentials")
+	scopes := fs.String("scopes", "", "comma separated scopes the client may request")
+	audiences := fs.String("audiences", "", "comma separated aud of issued access tokens, JWT_AUDIENCE when empty")
+	redirectURIs := fs.String("redirect-uris", "", "comma separated redirect URIs")
+	accessTTL := fs.Duration("access-ttl", 0, "access token lifetime, JWT_ACCESS_TTL when 0")
+	refreshTTL := fs.Duration("refresh-ttl", 0, "refresh token lifetime, JWT_REFRESH_TTL when 0")
+	public := fs.Bool("public", false, "register a public client without a secret (PKCE only)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client := models.OAuthClient{
+		ID:              *id,
+		Name:            *name,
+		GrantTypes:      splitFlag(*grants),
+		Scopes:          splitFlag(*scopes),
+		Audiences:       splitFlag(*audiences),
+		RedirectURIs:    splitFlag(*redirectURIs),
+		AccessTokenTTL:  *accessTTL,
+		RefreshTokenTTL: *refreshTTL,
+	}
+	if client.ID == "" {
+		return fmt.Errorf("clients create: -id is required")
+	}
+	for _, grant := range client.GrantTypes {
+		if grant != models.GrantAuthorizationCode && grant != models.GrantClientCredentials {
+			return fmt.Errorf("clients create: unsupported grant type %q", grant)
+		}
+	}
+	if client.AllowsGrant(models.GrantAuthorizationCode) && len(client.RedirectURIs) == 0 {
+		return fmt.Errorf("clients create: authorization_code requires -redirect-uris")
+	}
+	if client.AllowsGrant(models.GrantClientCredentials) && *public {
+		return fmt.Errorf("clients create: client_credentials requires a confidential client")
+	}
+
+	var secret string
+	if !*public {
+		var err error
+		if secret, err = auth.GenerateClientSecret(); err != nil {
+			return err
+		}
+		if client.SecretHash, err = auth.HashPassword(secret, config.GetConfig().Password.Argon2); err != nil {
+			return fmt.Errorf("hash client secret: %w", err)
+		}
+	}
+
+	client, err := repo.CreateOAuthClient(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("client_id", client.ID)
+	if secret != "" {
+		fmt.Println("client_secret", secret, "(shown only once)")
+	}
+	return nil
+}
+
+func listClients(ctx context.Context, repo repository.RepositoryI) error {
+	clients, err := repo.ListOAuthClients(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, client := range clients {
+		kind := "public"
+		if client.Confidential() {
+			kind = "confidential"
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\t%s\n", client.ID, client.Name, kind,
+			strings.Join(client.GrantTypes, ","), strings.Join(client.Scopes, ","),
+			client.CreatedAt.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func splitFlag(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
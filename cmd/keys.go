@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"auth-service/config"
+	"auth-service/internal/auth"
+	"flag"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+func runKeysCommand(args []string) error {
+	if len(args) == 0 {
+		printUsage()
+		return fmt.Errorf("keys: missing subcommand")
+	}
+
+	cfg := config.GetConfig().JWT
+
+	switch args[0] {
+	case "list":
+		return listKeys(cfg)
+	case "rotate":
+		return rotateKeys(cfg, args[1:])
+	case "prune":
+		pruned, err := auth.PruneKeys(cfg, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, kid := range pruned {
+			fmt.Println("pruned", kid)
+		}
+		return nil
+	default:
+		printUsage()
+		return fmt.Errorf("keys: unknown subcommand %q", args[0])
+	}
+}
+
+func listKeys(cfg config.JWT) error {
+	keys, err := auth.ListKeys(cfg)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, key := range keys {
+		status := "active"
+		switch {
+		case !key.CanVerify(now, auth.RefreshGracePeriod(cfg)):
+			status = "expired"
+		case !key.CanVerify(now, cfg.KeyGracePeriod):
+			status = "refresh only until " + key.RetiredAt.Add(auth.RefreshGracePeriod(cfg)).Format(time.RFC3339)
+		case key.IsRetired(now):
+			status = "retired until " + key.RetiredAt.Add(cfg.KeyGracePeriod).Format(time.RFC3339)
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", key.ID, key.Method.Alg(), key.CreatedAt.Format(time.RFC3339), status)
+	}
+
+	return nil
+}
+
+func rotateKeys(cfg config.JWT, args []string) error {
+	fs := flag.NewFlagSet("keys rotate", flag.ContinueOnError)
+	alg := fs.String("alg", cfg.Algorithm, "signing algorithm: HS512, RS256, ES256 or EdDSA")
+	olderThan := fs.Duration("if-older-than", 0, "rotate only when the active key is older than this")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	method := jwt.GetSigningMethod(*alg)
+	if method == nil {
+		return fmt.Errorf("unsupported jwt algorithm %q", *alg)
+	}
+
+	now := time.Now()
+	if *olderThan > 0 {
+		if kr, err := auth.LoadKeyring(cfg); err == nil && now.Sub(kr.Active().CreatedAt) < *olderThan {
+			fmt.Println("active key", kr.Active().ID, "is newer than", olderThan.String(), "- nothing to do")
+			return nil
+		}
+	}
+
+	key, err := auth.RotateKeys(cfg, method, now)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("new active key", key.ID, key.Method.Alg())
+	return nil
+}
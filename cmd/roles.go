@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"auth-service/database"
+	"auth-service/internal/repository"
+	"auth-service/models"
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// runRolesCommand assigns roles from the command line, which is the only way
+// to grant the first admin.
+func runRolesCommand(args []string) error {
+	if len(args) == 0 {
+		printUsage()
+		return fmt.Errorf("roles: missing subcommand")
+	}
+
+	ctx := context.Background()
+	conn := database.InitPostgres(ctx)
+	defer conn.Close()
+
+	repo := repository.NewRepository(conn)
+
+	switch args[0] {
+	case "list":
+		return listRoles(ctx, repo)
+	case "assign":
+		return assignRole(ctx, repo, args[1:])
+	default:
+		printUsage()
+		return fmt.Errorf("roles: unknown subcommand %q", args[0])
+	}
+}
+
+func listRoles(ctx context.Context, repo repository.RepositoryI) error {
+	roles, err := repo.ListRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		fmt.Printf("%s\t%s\t%s\n", role.Name, strings.Join(role.Permissions, ","), role.Description)
+	}
+
+	return nil
+}
+
+func assignRole(ctx context.Context, repo repository.RepositoryI, args []string) error {
+	fs := flag.NewFlagSet("roles assign", flag.ContinueOnError)
+	userID := fs.String("user", "", "user id")
+	role := fs.String("role", "", "role name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *userID == "" || *role == "" {
+		return fmt.Errorf("roles assign: -user and -role are required")
+	}
+
+	event := models.AuditEvent{
+		UserID:  *userID,
+		Type:    models.AuditRoleAssigned,
+		Details: map[string]interface{}{"role": *role, "actor": "cli"},
+	}
+	if err := repo.AssignRole(ctx, *userID, *role, event); err != nil {
+		return err
+	}
+
+	fmt.Println("role", *role, "assigned to", *userID)
+	return nil
+}
@@ -3,9 +3,14 @@ package cmd
 import (
 	"auth-service/config"
 	"auth-service/database"
+	"auth-service/internal/auth"
+	"auth-service/internal/binding"
 	"auth-service/internal/handler"
+	"auth-service/internal/ratelimit"
 	"auth-service/internal/repository"
+	"auth-service/internal/revocation"
 	"auth-service/internal/service"
+	"auth-service/internal/utils"
 	"context"
 	"errors"
 	"fmt"
@@ -18,17 +23,69 @@ import (
 )
 
 func Run() {
+	if len(os.Args) > 1 {
+		if err := runAdminCommand(os.Args[1:]); err != nil {
+			slog.Fatal("Command failed", "error", err)
+		}
+		return
+	}
+
 	cfg := config.GetConfig()
 
+	if err := auth.Init(cfg.JWT); err != nil {
+		slog.Fatal("Failed to load jwt signing keys", "error", err)
+	}
+
+	if err := utils.SetTrustedProxies(cfg.Server.TrustedProxies, cfg.Server.TrustedProxyHeader); err != nil {
+		slog.Fatal("Invalid TRUSTED_PROXIES or TRUSTED_PROXY_HEADER", "error", err)
+	}
+
+	if cfg.Webhook.URL != "" && len(cfg.Webhook.Secrets) == 0 {
+		slog.Fatal("WEBHOOK_SECRETS is required when WEBHOOK_URL is set")
+	}
+
+	bindingPolicy, err := binding.NewPolicy(cfg.Binding)
+	if err != nil {
+		slog.Fatal("Invalid BINDING_* configuration", "error", err)
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	go auth.WatchKeyring(ctx, cfg.JWT)
+
 	conn := database.InitPostgres(ctx)
 	defer conn.Close()
 
 	repo := repository.NewRepository(conn)
-	svc := service.NewService(repo)
-	router := handler.NewHandler(svc)
+	denylist := revocation.NewDenylist()
+	var store ratelimit.Store
+	switch cfg.RateLimit.Store {
+	case "memory":
+		store = ratelimit.NewMemoryStore()
+	case "postgres":
+		store = repo
+	default:
+		slog.Fatal("Invalid RATE_LIMIT_STORE", "store", cfg.RateLimit.Store)
+	}
+	limiter := ratelimit.NewLimiter(store, cfg.RateLimit, cfg.MFA)
+	go limiter.RunCleanup(ctx, time.Minute)
+
+	svc := service.NewService(repo, cfg, denylist, bindingPolicy, limiter)
+
+	if err := svc.ImportLegacyClients(ctx); err != nil {
+		slog.Fatal("Failed to import OAUTH_CLIENTS", "error", err)
+	}
+
+	if err := svc.LoadRevocations(ctx); err != nil {
+		slog.Fatal("Failed to load revoked tokens", "error", err)
+	}
+	go denylist.RunCleanup(ctx, time.Minute)
+	go svc.RunClientTTLRefresh(ctx, time.Minute)
+	go svc.RunRevocationSync(ctx)
+	go svc.RunOutboxDispatcher(ctx)
+
+	router := handler.NewHandler(svc, limiter)
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
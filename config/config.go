@@ -1,19 +1,35 @@
 package config
 
 import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/spf13/viper"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Server   Server
-	Postgres Postgres
-	JWT      JWT
-	Webhook  Webhook
+	Server        Server
+	Postgres      Postgres
+	JWT           JWT
+	Webhook       Webhook
+	Introspection Introspection
+	Password      Password
+	MFA           MFA
+	WebAuthn      WebAuthn
+	OAuth         OAuth
+	OIDC          OIDC
+	Binding       Binding
+	RateLimit     RateLimit
 }
 
 type Server struct {
-	Host string
-	Port int
+	Host           string
+	Port           int
+	TrustedProxies []string
+	// TrustedProxyHeader is the one forwarding header the trusted proxies set.
+	TrustedProxyHeader string
 }
 
 type Postgres struct {
@@ -26,25 +42,187 @@ type Postgres struct {
 }
 
 type JWT struct {
-	Secret string
+	Secret                string
+	PreviousSecrets       []string
+	Algorithm             string
+	PrivateKeyPath        string
+	KeysDir               string
+	KeyGracePeriod        time.Duration
+	KeyringReloadInterval time.Duration
+	RefreshAccessMaxAge   time.Duration
+	AccessTTL             time.Duration
+	RefreshTTL            time.Duration
+	SessionMaxLifetime    time.Duration
+	RefreshHashKey        string
+	LegacyRefreshUntil    time.Time
+	Audience              string
 }
 
+// Webhook configures the delivery of outbox events to URL: each attempt is
+// bounded by Timeout, failures are retried with a backoff growing from
+// RetryMin to RetryMax until MaxAttempts, then the event is dead-lettered.
+// Deliveries are signed with every one of Secrets.
 type Webhook struct {
-	URL string
+	URL          string
+	Secrets      []string
+	Timeout      time.Duration
+	MaxAttempts  int
+	RetryMin     time.Duration
+	RetryMax     time.Duration
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+type Password struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	Argon2        Argon2
+}
+
+type Argon2 struct {
+	MemoryKiB   uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// MFA configures the second login step. MaxAttempts wrong codes burn an
+// mfa_pending token, LockoutMaxFailures wrong codes within LockoutWindow lock
+// the user out of the second step for LockoutDuration.
+type MFA struct {
+	Issuer             string
+	PendingTTL         time.Duration
+	RecoveryCodesCount int
+	RecoveryCodeKey    string
+	MaxAttempts        int
+	LockoutMaxFailures int
+	LockoutWindow      time.Duration
+	LockoutDuration    time.Duration
+}
+
+type WebAuthn struct {
+	RPID         string
+	RPName       string
+	Origins      []string
+	ChallengeTTL time.Duration
+}
+
+// OAuth configures the authorization endpoint. /authorize has no login page,
+// so prompt=login and max_age=0 accept sessions authenticated within
+// PromptLoginMaxAge instead.
+type OAuth struct {
+	// Clients of the deprecated OAUTH_CLIENTS with their redirect URIs.
+	Clients           map[string][]string
+	CodeTTL           time.Duration
+	PromptLoginMaxAge time.Duration
+}
+
+type OIDC struct {
+	Issuer string
+}
+
+// Binding configures how the client presenting a refresh token is compared
+// with the one it was issued to.
+type Binding struct {
+	UserAgent       string
+	UserAgentAction string
+	IP              string
+	IPAction        string
+	IPv4PrefixLen   int
+	IPv6PrefixLen   int
+	ASNDatabase     string
+}
+
+// RateLimit configures the token buckets of the token endpoints and the
+// lockout after repeated refresh token mismatches. Zero bursts disable them.
+type RateLimit struct {
+	Store              string
+	IPBurst            int
+	IPPeriod           time.Duration
+	UserBurst          int
+	UserPeriod         time.Duration
+	LockoutMaxFailures int
+	LockoutWindow      time.Duration
+	LockoutDuration    time.Duration
+}
+
+type Introspection struct {
+	Clients map[string]string
 }
 
 func GetConfig() Config {
 	viper.SetConfigFile(".env")
+	viper.SetDefault("TRUSTED_PROXY_HEADER", "X-Forwarded-For")
+	viper.SetDefault("JWT_ALGORITHM", "HS512")
+	viper.SetDefault("PASSWORD_MIN_LENGTH", 10)
+	viper.SetDefault("PASSWORD_MAX_LENGTH", 128)
+	viper.SetDefault("ARGON2_MEMORY_KIB", 64*1024)
+	viper.SetDefault("ARGON2_ITERATIONS", 3)
+	viper.SetDefault("ARGON2_PARALLELISM", 2)
+	viper.SetDefault("MFA_ISSUER", "auth-service")
+	viper.SetDefault("MFA_PENDING_TTL", "5m")
+	viper.SetDefault("MFA_RECOVERY_CODES_COUNT", 10)
+	viper.SetDefault("MFA_MAX_ATTEMPTS", 5)
+	viper.SetDefault("MFA_LOCKOUT_MAX_FAILURES", 10)
+	viper.SetDefault("MFA_LOCKOUT_WINDOW", "15m")
+	viper.SetDefault("MFA_LOCKOUT_DURATION", "15m")
+	viper.SetDefault("WEBAUTHN_RP_NAME", "auth-service")
+	viper.SetDefault("WEBAUTHN_CHALLENGE_TTL", "5m")
+	viper.SetDefault("OAUTH_CODE_TTL", "1m")
+	viper.SetDefault("OAUTH_PROMPT_LOGIN_MAX_AGE", "1m")
+	viper.SetDefault("OIDC_ISSUER", "http://localhost:8080")
+	viper.SetDefault("JWT_KEY_GRACE_PERIOD", "24h")
+	viper.SetDefault("JWT_KEYRING_RELOAD_INTERVAL", "1m")
+	viper.SetDefault("JWT_REFRESH_ACCESS_MAX_AGE", "720h")
+	viper.SetDefault("JWT_ACCESS_TTL", "15m")
+	viper.SetDefault("JWT_REFRESH_TTL", "720h")
+	viper.SetDefault("JWT_SESSION_MAX_LIFETIME", "2160h")
+	viper.SetDefault("JWT_AUDIENCE", "auth-service")
+	viper.SetDefault("BINDING_USER_AGENT", "family")
+	viper.SetDefault("BINDING_USER_AGENT_ACTION", "deny")
+	viper.SetDefault("BINDING_IP", "exact")
+	viper.SetDefault("BINDING_IP_ACTION", "notify")
+	viper.SetDefault("BINDING_IPV4_PREFIX_LEN", 24)
+	viper.SetDefault("BINDING_IPV6_PREFIX_LEN", 48)
+	viper.SetDefault("WEBHOOK_TIMEOUT", "5s")
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 10)
+	viper.SetDefault("WEBHOOK_RETRY_MIN", "10s")
+	viper.SetDefault("WEBHOOK_RETRY_MAX", "1h")
+	viper.SetDefault("WEBHOOK_POLL_INTERVAL", "2s")
+	viper.SetDefault("WEBHOOK_BATCH_SIZE", 50)
+	viper.SetDefault("RATE_LIMIT_STORE", "memory")
+	viper.SetDefault("RATE_LIMIT_IP_BURST", 30)
+	viper.SetDefault("RATE_LIMIT_IP_PERIOD", "1m")
+	viper.SetDefault("RATE_LIMIT_USER_BURST", 10)
+	viper.SetDefault("RATE_LIMIT_USER_PERIOD", "1m")
+	viper.SetDefault("REFRESH_LOCKOUT_MAX_FAILURES", 5)
+	viper.SetDefault("REFRESH_LOCKOUT_WINDOW", "15m")
+	viper.SetDefault("REFRESH_LOCKOUT_DURATION", "15m")
 
 	err := viper.ReadInConfig()
 	if err != nil {
 		panic("Failed to read .env file: " + err.Error())
 	}
 
+	refreshHashKey := viper.GetString("REFRESH_TOKEN_HASH_KEY")
+	if refreshHashKey == "" {
+		refreshHashKey = viper.GetString("SECRET_KEY")
+	}
+
+	recoveryCodeKey := viper.GetString("MFA_RECOVERY_CODE_KEY")
+	if recoveryCodeKey == "" {
+		recoveryCodeKey = deriveKey(viper.GetString("SECRET_KEY"), "auth-service mfa recovery codes")
+	}
+
 	return Config{
 		Server: Server{
-			Host: viper.GetString("SRV_HOST"),
-			Port: viper.GetInt("SRV_PORT"),
+			Host:               viper.GetString("SRV_HOST"),
+			Port:               viper.GetInt("SRV_PORT"),
+			TrustedProxies:     splitList(viper.GetString("TRUSTED_PROXIES")),
+			TrustedProxyHeader: viper.GetString("TRUSTED_PROXY_HEADER"),
 		},
 		Postgres: Postgres{
 			Username: viper.GetString("POSTGRES_USER"),
@@ -54,10 +232,136 @@ func GetConfig() Config {
 			DBName:   viper.GetString("POSTGRES_DB"),
 		},
 		JWT: JWT{
-			Secret: viper.GetString("SECRET_KEY"),
+			Secret:                viper.GetString("SECRET_KEY"),
+			PreviousSecrets:       splitList(viper.GetString("JWT_PREVIOUS_SECRETS")),
+			Algorithm:             viper.GetString("JWT_ALGORITHM"),
+			PrivateKeyPath:        viper.GetString("JWT_PRIVATE_KEY_PATH"),
+			KeysDir:               viper.GetString("JWT_KEYS_DIR"),
+			KeyGracePeriod:        viper.GetDuration("JWT_KEY_GRACE_PERIOD"),
+			KeyringReloadInterval: viper.GetDuration("JWT_KEYRING_RELOAD_INTERVAL"),
+			RefreshAccessMaxAge:   viper.GetDuration("JWT_REFRESH_ACCESS_MAX_AGE"),
+			AccessTTL:             viper.GetDuration("JWT_ACCESS_TTL"),
+			RefreshTTL:            viper.GetDuration("JWT_REFRESH_TTL"),
+			SessionMaxLifetime:    viper.GetDuration("JWT_SESSION_MAX_LIFETIME"),
+			RefreshHashKey:        refreshHashKey,
+			LegacyRefreshUntil:    viper.GetTime("REFRESH_LEGACY_ACCEPT_UNTIL"),
+			Audience:              viper.GetString("JWT_AUDIENCE"),
 		},
 		Webhook: Webhook{
-			URL: viper.GetString("WEBHOOK_URL"),
+			URL:          viper.GetString("WEBHOOK_URL"),
+			Secrets:      splitList(viper.GetString("WEBHOOK_SECRETS")),
+			Timeout:      viper.GetDuration("WEBHOOK_TIMEOUT"),
+			MaxAttempts:  viper.GetInt("WEBHOOK_MAX_ATTEMPTS"),
+			RetryMin:     viper.GetDuration("WEBHOOK_RETRY_MIN"),
+			RetryMax:     viper.GetDuration("WEBHOOK_RETRY_MAX"),
+			PollInterval: viper.GetDuration("WEBHOOK_POLL_INTERVAL"),
+			BatchSize:    viper.GetInt("WEBHOOK_BATCH_SIZE"),
 		},
+		Introspection: Introspection{
+			Clients: parseClientCredentials(viper.GetString("INTROSPECTION_CLIENTS")),
+		},
+		Password: Password{
+			MinLength:     viper.GetInt("PASSWORD_MIN_LENGTH"),
+			MaxLength:     viper.GetInt("PASSWORD_MAX_LENGTH"),
+			RequireUpper:  viper.GetBool("PASSWORD_REQUIRE_UPPER"),
+			RequireLower:  viper.GetBool("PASSWORD_REQUIRE_LOWER"),
+			RequireDigit:  viper.GetBool("PASSWORD_REQUIRE_DIGIT"),
+			RequireSymbol: viper.GetBool("PASSWORD_REQUIRE_SYMBOL"),
+			Argon2: Argon2{
+				MemoryKiB:   viper.GetUint32("ARGON2_MEMORY_KIB"),
+				Iterations:  viper.GetUint32("ARGON2_ITERATIONS"),
+				Parallelism: uint8(viper.GetUint("ARGON2_PARALLELISM")),
+			},
+		},
+		MFA: MFA{
+			Issuer:             viper.GetString("MFA_ISSUER"),
+			PendingTTL:         viper.GetDuration("MFA_PENDING_TTL"),
+			RecoveryCodesCount: viper.GetInt("MFA_RECOVERY_CODES_COUNT"),
+			RecoveryCodeKey:    recoveryCodeKey,
+			MaxAttempts:        viper.GetInt("MFA_MAX_ATTEMPTS"),
+			LockoutMaxFailures: viper.GetInt("MFA_LOCKOUT_MAX_FAILURES"),
+			LockoutWindow:      viper.GetDuration("MFA_LOCKOUT_WINDOW"),
+			LockoutDuration:    viper.GetDuration("MFA_LOCKOUT_DURATION"),
+		},
+		WebAuthn: WebAuthn{
+			RPID:         viper.GetString("WEBAUTHN_RP_ID"),
+			RPName:       viper.GetString("WEBAUTHN_RP_NAME"),
+			Origins:      splitList(viper.GetString("WEBAUTHN_ORIGINS")),
+			ChallengeTTL: viper.GetDuration("WEBAUTHN_CHALLENGE_TTL"),
+		},
+		OAuth: OAuth{
+			Clients:           parseRedirectURIs(viper.GetString("OAUTH_CLIENTS")),
+			CodeTTL:           viper.GetDuration("OAUTH_CODE_TTL"),
+			PromptLoginMaxAge: viper.GetDuration("OAUTH_PROMPT_LOGIN_MAX_AGE"),
+		},
+		OIDC: OIDC{
+			Issuer: strings.TrimSuffix(viper.GetString("OIDC_ISSUER"), "/"),
+		},
+		Binding: Binding{
+			UserAgent:       viper.GetString("BINDING_USER_AGENT"),
+			UserAgentAction: viper.GetString("BINDING_USER_AGENT_ACTION"),
+			IP:              viper.GetString("BINDING_IP"),
+			IPAction:        viper.GetString("BINDING_IP_ACTION"),
+			IPv4PrefixLen:   viper.GetInt("BINDING_IPV4_PREFIX_LEN"),
+			IPv6PrefixLen:   viper.GetInt("BINDING_IPV6_PREFIX_LEN"),
+			ASNDatabase:     viper.GetString("BINDING_ASN_DATABASE"),
+		},
+		RateLimit: RateLimit{
+			Store:              viper.GetString("RATE_LIMIT_STORE"),
+			IPBurst:            viper.GetInt("RATE_LIMIT_IP_BURST"),
+			IPPeriod:           viper.GetDuration("RATE_LIMIT_IP_PERIOD"),
+			UserBurst:          viper.GetInt("RATE_LIMIT_USER_BURST"),
+			UserPeriod:         viper.GetDuration("RATE_LIMIT_USER_PERIOD"),
+			LockoutMaxFailures: viper.GetInt("REFRESH_LOCKOUT_MAX_FAILURES"),
+			LockoutWindow:      viper.GetDuration("REFRESH_LOCKOUT_WINDOW"),
+			LockoutDuration:    viper.GetDuration("REFRESH_LOCKOUT_DURATION"),
+		},
+	}
+}
+
+// deriveKey derives a key for a single purpose from secret with HKDF-SHA256,
+// so a fallback to SECRET_KEY never shares the key with another use of it.
+func deriveKey(secret, label string) string {
+	key, err := hkdf.Key(sha256.New, []byte(secret), nil, label, sha256.Size)
+	if err != nil {
+		panic("Failed to derive key: " + err.Error())
+	}
+	return hex.EncodeToString(key)
+}
+
+// parseClientCredentials parses a "client_id:secret,client_id:secret" list.
+func parseClientCredentials(value string) map[string]string {
+	clients := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" || secret == "" {
+			continue
+		}
+		clients[id] = secret
+	}
+	return clients
+}
+
+// parseRedirectURIs reads "client_id=uri uri,client_id=uri" into the redirect
+// URIs registered for every client.
+func parseRedirectURIs(value string) map[string][]string {
+	clients := make(map[string][]string)
+	for _, entry := range strings.Split(value, ",") {
+		id, uris, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || id == "" {
+			continue
+		}
+		clients[id] = append(clients[id], strings.Fields(uris)...)
+	}
+	return clients
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
@@ -9,6 +9,7 @@ require (
 	github.com/gookit/slog v0.5.8
 	github.com/jackc/pgx/v5 v5.7.4
 	github.com/spf13/viper v1.20.1
+	github.com/swaggo/http-swagger v1.3.4
 	golang.org/x/crypto v0.39.0
 )
 
@@ -44,7 +45,6 @@ require (
 	github.com/spf13/pflag v1.0.6 // indirect
 	github.com/subosito/gotenv v1.6.0 // indirect
 	github.com/swaggo/files v1.0.1 // indirect
-	github.com/swaggo/http-swagger v1.3.4 // indirect
 	github.com/swaggo/swag v1.16.4 // indirect
 	github.com/urfave/cli/v2 v2.27.7 // indirect
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
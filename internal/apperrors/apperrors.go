@@ -1,12 +1,108 @@
 package apperrors
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
-	ErrTokenExpired     = errors.New("token expired")
-	ErrInvalidToken     = errors.New("invalid token")
-	ErrTokenRevoked     = errors.New("token revoked")
-	ErrTokenIsNotFound  = errors.New("token not found")
-	ErrUserDeauthorized = errors.New("user deauthorized")
-	ErrAlreadyLoggedOut = errors.New("user already logged out")
+	ErrTokenExpired        = errors.New("token expired")
+	ErrRefreshExpired      = errors.New("refresh token expired")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenRevoked        = errors.New("token revoked")
+	ErrTokenReused         = errors.New("refresh token reused")
+	ErrTokenIsNotFound     = errors.New("token not found")
+	ErrUserDeauthorized    = errors.New("user deauthorized")
+	ErrStepUpRequired      = errors.New("re-authentication required")
+	ErrTooManyAttempts     = errors.New("too many failed attempts")
+	ErrAlreadyLoggedOut    = errors.New("user already logged out")
+	ErrInvalidClient       = errors.New("invalid client credentials")
+	ErrInvalidCursor       = errors.New("invalid pagination cursor")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrUserExists          = errors.New("user already exists")
+	ErrInvalidEmail        = errors.New("invalid email")
+	ErrWeakPassword        = errors.New("password does not satisfy the policy")
+	ErrBadCredentials      = errors.New("invalid email or password")
+	ErrMFANotEnrolled      = errors.New("mfa is not enrolled")
+	ErrMFAAlreadyEnabled   = errors.New("mfa is already enabled")
+	ErrInvalidMFACode      = errors.New("invalid mfa code")
+	ErrChallengeNotFound   = errors.New("webauthn challenge not found or expired")
+	ErrCredentialNotFound  = errors.New("webauthn credential not found")
+	ErrCredentialExists    = errors.New("webauthn credential already registered")
+	ErrCredentialCloned    = errors.New("webauthn credential is disabled as cloned")
+	ErrInvalidCredential   = errors.New("webauthn verification failed")
+	ErrInvalidRedirectURI  = errors.New("redirect uri is not registered for the client")
+	ErrCodeNotFound        = errors.New("authorization code not found or expired")
+	ErrCodeReused          = errors.New("authorization code already used")
+	ErrClientNotFound      = errors.New("oauth client not found")
+	ErrClientExists        = errors.New("oauth client already exists")
+	ErrRoleNotFound        = errors.New("role not found")
+	ErrRoleExists          = errors.New("role already exists")
+	ErrRoleNotAssigned     = errors.New("role is not assigned to the user")
+	ErrInvalidRoleName     = errors.New("invalid role name")
+	ErrUnknownPermission   = errors.New("unknown permission")
+	ErrOutboxEventNotFound = errors.New("outbox event not found")
+	ErrOutboxEventNotDead  = errors.New("outbox event is not dead-lettered")
+	ErrOIDCUnavailable     = errors.New("openid connect requires an asymmetric signing key")
+)
+
+// Error codes of RFC 6749 sections 4.1.2.1 and 5.2, and insufficient_scope
+// of RFC 6750 section 3.1.
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthInsufficientScope       = "insufficient_scope"
+	OAuthLoginRequired           = "login_required"
+	OAuthServerError             = "server_error"
 )
+
+// OAuthError is returned by the OAuth endpoints. Its description is sent to
+// the client, so it must stay ASCII as RFC 6749 requires.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// LockoutError is returned while a user is locked out after repeated invalid
+// refresh tokens or second factor codes.
+type LockoutError struct {
+	Until time.Time
+}
+
+func (e *LockoutError) Error() string {
+	return "locked out until " + e.Until.Format(time.RFC3339)
+}
+
+func (e *LockoutError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// RateLimitError is returned when the bucket of a user charged by the service
+// itself, past the middleware, is empty.
+type RateLimitError struct {
+	Limit      int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return "rate limit exceeded, retry after " + e.RetryAfter.String()
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrTooManyAttempts
+}
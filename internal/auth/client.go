@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// GenerateClientSecret returns a random secret shown once when a client is
+// registered. Only its argon2id hash is stored.
+func GenerateClientSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generate client secret: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
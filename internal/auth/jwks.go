@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"auth-service/models"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+func PublicJWKS() (models.JWKSet, error) {
+	kr, err := currentKeyring()
+	if err != nil {
+		return models.JWKSet{}, err
+	}
+
+	set := models.JWKSet{Keys: []models.JWK{}}
+	for _, key := range kr.VerificationKeys(time.Now()) {
+		if !key.IsAsymmetric() {
+			continue
+		}
+
+		jwk, err := PublicJWK(key)
+		if err != nil {
+			return models.JWKSet{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set, nil
+}
+
+func PublicJWK(key *SigningKey) (models.JWK, error) {
+	jwk := models.JWK{
+		Use: "sig",
+		Alg: key.Method.Alg(),
+		Kid: key.ID,
+	}
+
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64URL(pub.N.Bytes())
+		jwk.E = encodeBase64URL(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = encodeBase64URL(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64URL(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64URL(pub)
+	default:
+		return models.JWK{}, fmt.Errorf("key of type %T can not be published", key.Public)
+	}
+
+	return jwk, nil
+}
+
+func encodeBase64URL(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -1,49 +1,80 @@
 package auth
 
 import (
-	"auth-service/config"
-	"crypto/rand"
-	"encoding/base64"
+	"auth-service/models"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"slices"
 	"time"
 )
 
-func GenerateRefreshToken() (string, string, error) {
-	tokenBytes := make([]byte, 32)
-	_, err := rand.Read(tokenBytes)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to generate random bytes for refresh token: %w", err)
+func GenerateAccessToken(grant models.Grant, tokenPairID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub":           grant.UserID,
+		"user_id":       grant.UserID,
+		"user_ip":       grant.IP,
+		"user_agent":    grant.UserAgent,
+		"token_pair_id": tokenPairID,
+		"aud":           audienceClaim(grant.Audience),
+		"iat":           now.Unix(),
+		"exp":           now.Add(ttl).Unix(),
 	}
-
-	tokenBase64 := base64.URLEncoding.EncodeToString(tokenBytes)
-
-	hash, err := bcrypt.GenerateFromPassword(tokenBytes, bcrypt.DefaultCost)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to hash refresh token bytes: %w", err)
+	if len(grant.AMR) > 0 {
+		claims["amr"] = grant.AMR
+	}
+	if grant.ClientID != "" {
+		claims["client_id"] = grant.ClientID
+	}
+	if grant.Scope != "" {
+		claims["scope"] = grant.Scope
+	}
+	if len(grant.Roles) > 0 {
+		claims["roles"] = grant.Roles
 	}
 
-	return tokenBase64, string(hash), nil
+	return signClaims(claims)
 }
 
-func GenerateAccessToken(userID, userIP, userAgent, tokenPairID string) (string, error) {
+// GenerateClientAccessToken issues a token of the client_credentials grant.
+// Its subject is the client itself, it has no user_id and no token pair.
+func GenerateClientAccessToken(clientID, scope string, aud []string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"user_id":       userID,
-		"user_ip":       userIP,
-		"user_agent":    userAgent,
-		"token_pair_id": tokenPairID,
-		"exp":           time.Now().Add(15 * time.Minute).Unix(),
+		"sub":       clientID,
+		"client_id": clientID,
+		"aud":       audienceClaim(aud),
+		"iat":       now.Unix(),
+		"exp":       now.Add(ttl).Unix(),
 	}
+	if scope != "" {
+		claims["scope"] = scope
+	}
+
+	return signClaims(claims)
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
+// audienceClaim defaults aud to JWT_AUDIENCE, the audience of this service.
+func audienceClaim(aud []string) jwt.ClaimStrings {
+	if len(aud) == 0 {
+		return jwt.ClaimStrings{ownAudience()}
+	}
+	return aud
+}
 
-	secretKey := config.GetConfig().JWT.Secret
-	if secretKey == "" {
-		return "", fmt.Errorf("jwt secret key is not configured")
+func signClaims(claims jwt.MapClaims) (string, error) {
+	kr, err := currentKeyring()
+	if err != nil {
+		return "", err
 	}
+	return signClaimsWith(kr.Active(), claims)
+}
+
+func signClaimsWith(key *SigningKey, claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
 
-	tokenString, err := token.SignedString([]byte(secretKey))
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", fmt.Errorf("sign jwt token: %w", err)
 	}
@@ -51,18 +82,108 @@ func GenerateAccessToken(userID, userIP, userAgent, tokenPairID string) (string,
 	return tokenString, nil
 }
 
+// ParseAndValidateToken verifies a token presented to this service: its aud
+// must include JWT_AUDIENCE.
 func ParseAndValidateToken(tokenString string) (jwt.MapClaims, error) {
-	secretKey := config.GetConfig().JWT.Secret
-	if secretKey == "" {
-		return nil, fmt.Errorf("jwt secret key is not configured")
+	return parseToken(tokenString, jwt.WithAudience(ownAudience()))
+}
+
+// ParseTokenAnyAudience verifies a token issued for any audience, as
+// introspection on behalf of other resource servers needs.
+func ParseTokenAnyAudience(tokenString string) (jwt.MapClaims, error) {
+	return parseToken(tokenString)
+}
+
+// verifyOwnAudience checks the aud of a token parsed without claims
+// validation the way jwt.WithAudience(ownAudience()) would.
+func verifyOwnAudience(claims jwt.MapClaims) error {
+	own := ownAudience()
+	if own == "" {
+		return nil
+	}
+	aud, err := claims.GetAudience()
+	if err != nil || !slices.Contains(aud, own) {
+		return fmt.Errorf("token is not issued for %q: %w", own, jwt.ErrTokenInvalidAudience)
+	}
+	return nil
+}
+
+// ParseTokenIgnoringExpiry verifies the signature and aud of a token but
+// accepts it after its exp has passed.
+func ParseTokenIgnoringExpiry(tokenString string) (jwt.MapClaims, error) {
+	claims, err := parseToken(tokenString, jwt.WithoutClaimsValidation())
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyOwnAudience(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ParseTokenForRefresh verifies an access token presented for rotation. Its exp
+// is ignored, instead the token must have been issued no longer than maxAge ago.
+// Retired keys verify it for RefreshGracePeriod.
+func ParseTokenForRefresh(tokenString string, maxAge time.Duration) (jwt.MapClaims, error) {
+	claims, err := verifyToken(tokenString, true, jwt.WithoutClaimsValidation())
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyOwnAudience(claims); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return nil, fmt.Errorf("iat is missing in token claims")
+	}
+	if issuedAt.After(now.Add(time.Minute)) {
+		return nil, fmt.Errorf("token used before issued: %w", jwt.ErrTokenUsedBeforeIssued)
+	}
+	if now.Sub(issuedAt.Time) > maxAge {
+		return nil, fmt.Errorf("token is older than %s: %w", maxAge, jwt.ErrTokenExpired)
+	}
+
+	if notBefore, err := claims.GetNotBefore(); err != nil || (notBefore != nil && now.Before(notBefore.Time)) {
+		return nil, fmt.Errorf("token is not valid yet: %w", jwt.ErrTokenNotValidYet)
+	}
+
+	return claims, nil
+}
+
+func parseToken(tokenString string, opts ...jwt.ParserOption) (jwt.MapClaims, error) {
+	return verifyToken(tokenString, false, opts...)
+}
+
+// verifyToken parses a token signed by a key of the keyring. Retired keys
+// count for the refresh grace period when forRefresh is set.
+func verifyToken(tokenString string, forRefresh bool, opts ...jwt.ParserOption) (jwt.MapClaims, error) {
+	kr, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	grace := kr.grace
+	if forRefresh {
+		grace = kr.refreshGrace
 	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || token.Method.Alg() != jwt.SigningMethodHS512.Alg() {
-			return nil, fmt.Errorf("invalid signing method")
+		kid, hasKid := token.Header["kid"].(string)
+
+		var set jwt.VerificationKeySet
+		for _, key := range kr.candidates(kid, hasKid, time.Now(), grace) {
+			if token.Method.Alg() == key.Method.Alg() {
+				set.Keys = append(set.Keys, key.Public)
+			}
+		}
+		if len(set.Keys) == 0 {
+			return nil, fmt.Errorf("unknown or expired signing key %q for %s", kid, token.Method.Alg())
 		}
-		return []byte(secretKey), nil
-	})
+		return set, nil
+	}, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse jwt token: %w", err)
 	}
@@ -0,0 +1,452 @@
+package auth
+
+import (
+	"auth-service/config"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gookit/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const keyringManifestFile = "keyring.json"
+
+type Keyring struct {
+	keys   map[string]*SigningKey
+	active *SigningKey
+	grace  time.Duration
+	// refreshGrace keeps retired keys verifying the access tokens presented
+	// for refresh, which may be up to JWT_REFRESH_ACCESS_MAX_AGE old.
+	refreshGrace time.Duration
+	// previous are former HS512 secrets, which only verify tokens.
+	previous []*SigningKey
+}
+
+type keyringManifest struct {
+	Keys []keyringEntry `json:"keys"`
+}
+
+type keyringEntry struct {
+	ID        string     `json:"kid"`
+	Algorithm string     `json:"alg"`
+	File      string     `json:"file"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
+
+var (
+	keyringMu sync.RWMutex
+	keyring   *Keyring
+	// audience is the aud of this service, see ParseAndValidateToken.
+	audience string
+)
+
+func Init(cfg config.JWT) error {
+	kr, err := LoadKeyring(cfg)
+	if err != nil {
+		return err
+	}
+
+	keyringMu.Lock()
+	keyring = kr
+	audience = cfg.Audience
+	keyringMu.Unlock()
+
+	return nil
+}
+
+// WatchKeyring periodically reloads the keyring from JWT_KEYS_DIR so that
+// rotations made by the admin command reach every running instance.
+func WatchKeyring(ctx context.Context, cfg config.JWT) {
+	if cfg.KeysDir == "" || cfg.KeyringReloadInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.KeyringReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			previous, _ := currentKeyring()
+			if err := Init(cfg); err != nil {
+				slog.Error("failed to reload jwt keyring", "error", err)
+				continue
+			}
+			current, _ := currentKeyring()
+			if previous != nil && previous.Active().ID != current.Active().ID {
+				slog.Info("jwt signing key rotated", "kid", current.Active().ID)
+			}
+		}
+	}
+}
+
+func currentKeyring() (*Keyring, error) {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+
+	if keyring == nil {
+		return nil, fmt.Errorf("jwt keyring is not initialized")
+	}
+	return keyring, nil
+}
+
+func ownAudience() string {
+	keyringMu.RLock()
+	defer keyringMu.RUnlock()
+	return audience
+}
+
+func NewKeyring(keys []*SigningKey, grace time.Duration, now time.Time) (*Keyring, error) {
+	kr := &Keyring{
+		keys:         make(map[string]*SigningKey, len(keys)),
+		grace:        grace,
+		refreshGrace: grace,
+	}
+
+	for _, key := range keys {
+		if _, exists := kr.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.ID)
+		}
+		kr.keys[key.ID] = key
+
+		if key.IsRetired(now) {
+			continue
+		}
+		if kr.active == nil || key.CreatedAt.After(kr.active.CreatedAt) {
+			kr.active = key
+		}
+	}
+
+	if kr.active == nil {
+		return nil, fmt.Errorf("jwt keyring has no active signing key")
+	}
+
+	return kr, nil
+}
+
+func (k *Keyring) Active() *SigningKey {
+	return k.active
+}
+
+func (k *Keyring) Lookup(kid string, now time.Time) (*SigningKey, bool) {
+	key, ok := k.keys[kid]
+	if !ok || !key.CanVerify(now, k.grace) {
+		return nil, false
+	}
+	return key, true
+}
+
+// candidates returns the keys a token with the given kid header may have been
+// signed with, counting retired keys for grace. Tokens without kid predate
+// the keyring and may have been signed by any of its keys. Previous secrets
+// are tried last.
+func (k *Keyring) candidates(kid string, hasKid bool, now time.Time, grace time.Duration) []*SigningKey {
+	var keys []*SigningKey
+	if !hasKid {
+		keys = k.verificationKeys(now, grace)
+	} else if key, ok := k.keys[kid]; ok && key.CanVerify(now, grace) {
+		keys = append(keys, key)
+	}
+	return append(keys, k.previous...)
+}
+
+func (k *Keyring) VerificationKeys(now time.Time) []*SigningKey {
+	return k.verificationKeys(now, k.grace)
+}
+
+func (k *Keyring) verificationKeys(now time.Time, grace time.Duration) []*SigningKey {
+	keys := make([]*SigningKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		if key.CanVerify(now, grace) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	return keys
+}
+
+func LoadKeyring(cfg config.JWT) (*Keyring, error) {
+	kr, err := loadKeyring(cfg, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	kr.refreshGrace = RefreshGracePeriod(cfg)
+
+	for i, secret := range cfg.PreviousSecrets {
+		kr.previous = append(kr.previous, &SigningKey{
+			ID:      fmt.Sprintf("previous-%d", i+1),
+			Method:  jwt.SigningMethodHS512,
+			Private: []byte(secret),
+			Public:  []byte(secret),
+		})
+	}
+
+	return kr, nil
+}
+
+func loadKeyring(cfg config.JWT, now time.Time) (*Keyring, error) {
+	if cfg.KeysDir == "" {
+		key, err := LoadSigningKey(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewKeyring([]*SigningKey{key}, cfg.KeyGracePeriod, now)
+	}
+
+	manifest, err := readKeyringManifest(cfg.KeysDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(manifest.Keys) == 0 {
+		return nil, fmt.Errorf("jwt keyring in %s is empty, run `keys rotate` first", cfg.KeysDir)
+	}
+
+	keys := make([]*SigningKey, 0, len(manifest.Keys))
+	for _, entry := range manifest.Keys {
+		key, err := loadKeyringEntry(cfg.KeysDir, entry)
+		if err != nil {
+			return nil, fmt.Errorf("load jwt key %q: %w", entry.ID, err)
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeyring(keys, cfg.KeyGracePeriod, now)
+}
+
+// RefreshGracePeriod is how long a retired key keeps verifying access tokens
+// presented for refresh: JWT_KEY_GRACE_PERIOD, but no less than
+// JWT_REFRESH_ACCESS_MAX_AGE, so a rotation never makes a refreshable session
+// unrefreshable.
+func RefreshGracePeriod(cfg config.JWT) time.Duration {
+	return max(cfg.KeyGracePeriod, cfg.RefreshAccessMaxAge)
+}
+
+// RotateKeys generates a new signing key in JWT_KEYS_DIR and retires the
+// previously active ones. Retired keys keep verifying tokens for JWT_KEY_GRACE_PERIOD.
+// The first rotation imports the key configured without JWT_KEYS_DIR, so the
+// tokens it signed survive the switch to the keyring for the grace period too.
+func RotateKeys(cfg config.JWT, method jwt.SigningMethod, now time.Time) (*SigningKey, error) {
+	if cfg.KeysDir == "" {
+		return nil, fmt.Errorf("JWT_KEYS_DIR is not configured")
+	}
+
+	if err := os.MkdirAll(cfg.KeysDir, 0o700); err != nil {
+		return nil, fmt.Errorf("create keys dir: %w", err)
+	}
+
+	manifest, err := readKeyringManifest(cfg.KeysDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(manifest.Keys) == 0 {
+		entry, err := importConfiguredKey(cfg, now)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			manifest.Keys = append(manifest.Keys, *entry)
+		}
+	}
+
+	key, pemBytes, err := GenerateSigningKey(method)
+	if err != nil {
+		return nil, err
+	}
+	key.CreatedAt = now
+
+	file := key.ID + ".pem"
+	if err = os.WriteFile(filepath.Join(cfg.KeysDir, file), pemBytes, 0o600); err != nil {
+		return nil, fmt.Errorf("write jwt key: %w", err)
+	}
+
+	for i := range manifest.Keys {
+		if manifest.Keys[i].RetiredAt == nil {
+			retiredAt := now
+			manifest.Keys[i].RetiredAt = &retiredAt
+		}
+	}
+	manifest.Keys = append(manifest.Keys, keyringEntry{
+		ID:        key.ID,
+		Algorithm: method.Alg(),
+		File:      file,
+		CreatedAt: now,
+	})
+
+	if err = writeKeyringManifest(cfg.KeysDir, manifest); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// importConfiguredKey copies the key of SECRET_KEY or JWT_PRIVATE_KEY_PATH
+// into JWT_KEYS_DIR under its kid, "default" for SECRET_KEY. Nothing is
+// imported when neither is configured.
+func importConfiguredKey(cfg config.JWT, now time.Time) (*keyringEntry, error) {
+	key, err := LoadSigningKey(cfg)
+	if err != nil {
+		slog.Warn("no configured jwt key to import into the keyring", "error", err)
+		return nil, nil
+	}
+
+	var pemBytes []byte
+	if key.IsAsymmetric() {
+		if pemBytes, err = os.ReadFile(cfg.PrivateKeyPath); err != nil {
+			return nil, fmt.Errorf("read jwt private key: %w", err)
+		}
+	} else {
+		pemBytes = pem.EncodeToMemory(&pem.Block{Type: hmacKeyPEMType, Bytes: key.Private.([]byte)})
+	}
+
+	file := key.ID + ".pem"
+	if err = os.WriteFile(filepath.Join(cfg.KeysDir, file), pemBytes, 0o600); err != nil {
+		return nil, fmt.Errorf("write jwt key: %w", err)
+	}
+
+	return &keyringEntry{
+		ID:        key.ID,
+		Algorithm: key.Method.Alg(),
+		File:      file,
+		CreatedAt: now,
+	}, nil
+}
+
+// PruneKeys removes keys whose grace period, including RefreshGracePeriod,
+// has ended and returns their ids.
+func PruneKeys(cfg config.JWT, now time.Time) ([]string, error) {
+	if cfg.KeysDir == "" {
+		return nil, fmt.Errorf("JWT_KEYS_DIR is not configured")
+	}
+
+	manifest, err := readKeyringManifest(cfg.KeysDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept, pruned []keyringEntry
+	for _, entry := range manifest.Keys {
+		if entry.RetiredAt == nil || now.Before(entry.RetiredAt.Add(RefreshGracePeriod(cfg))) {
+			kept = append(kept, entry)
+			continue
+		}
+		pruned = append(pruned, entry)
+	}
+	manifest.Keys = kept
+
+	if err = writeKeyringManifest(cfg.KeysDir, manifest); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(pruned))
+	for _, entry := range pruned {
+		if err = os.Remove(filepath.Join(cfg.KeysDir, entry.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("failed to remove pruned jwt key file", "kid", entry.ID, "error", err)
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	return ids, nil
+}
+
+// ListKeys returns every key of the keyring, newest first.
+func ListKeys(cfg config.JWT) ([]*SigningKey, error) {
+	kr, err := LoadKeyring(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*SigningKey, 0, len(kr.keys))
+	for _, key := range kr.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.After(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+func loadKeyringEntry(dir string, entry keyringEntry) (*SigningKey, error) {
+	method := jwt.GetSigningMethod(entry.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", entry.Algorithm)
+	}
+
+	pemBytes, err := os.ReadFile(filepath.Join(dir, entry.File))
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+
+	key, err := ParsePrivateKey(method, pemBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key.ID = entry.ID
+	key.CreatedAt = entry.CreatedAt
+	if entry.RetiredAt != nil {
+		key.RetiredAt = *entry.RetiredAt
+	}
+
+	return key, nil
+}
+
+func readKeyringManifest(dir string) (keyringManifest, error) {
+	var manifest keyringManifest
+
+	data, err := os.ReadFile(filepath.Join(dir, keyringManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("read keyring manifest: %w", err)
+	}
+
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("decode keyring manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func writeKeyringManifest(dir string, manifest keyringManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode keyring manifest: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, keyringManifestFile+".*")
+	if err != nil {
+		return fmt.Errorf("create keyring manifest: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write keyring manifest: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("write keyring manifest: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), filepath.Join(dir, keyringManifestFile)); err != nil {
+		return fmt.Errorf("replace keyring manifest: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"auth-service/config"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"time"
+)
+
+const hmacKeyPEMType = "HMAC KEY"
+
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   interface{}
+	Public    interface{}
+	CreatedAt time.Time
+	RetiredAt time.Time
+}
+
+func LoadSigningKey(cfg config.JWT) (*SigningKey, error) {
+	method := jwt.GetSigningMethod(cfg.Algorithm)
+	if method == nil {
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", cfg.Algorithm)
+	}
+
+	if method == jwt.SigningMethodHS512 {
+		if cfg.Secret == "" {
+			return nil, fmt.Errorf("jwt secret key is not configured")
+		}
+		return &SigningKey{ID: "default", Method: method, Private: []byte(cfg.Secret), Public: []byte(cfg.Secret)}, nil
+	}
+
+	if cfg.PrivateKeyPath == "" {
+		return nil, fmt.Errorf("jwt private key path is required for %s", cfg.Algorithm)
+	}
+
+	pemBytes, err := os.ReadFile(cfg.PrivateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt private key: %w", err)
+	}
+
+	key, err := ParsePrivateKey(method, pemBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key.ID, err = Thumbprint(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+func ParsePrivateKey(method jwt.SigningMethod, pemBytes []byte) (*SigningKey, error) {
+	var private crypto.Signer
+
+	switch method {
+	case jwt.SigningMethodHS512:
+		block, _ := pem.Decode(pemBytes)
+		if block == nil || block.Type != hmacKeyPEMType {
+			return nil, fmt.Errorf("parse hmac key: invalid PEM block")
+		}
+		return &SigningKey{Method: method, Private: block.Bytes, Public: block.Bytes}, nil
+	case jwt.SigningMethodRS256:
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse rsa private key: %w", err)
+		}
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("rsa private key must be at least 2048 bits")
+		}
+		private = key
+	case jwt.SigningMethodES256:
+		key, err := jwt.ParseECPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse ecdsa private key: %w", err)
+		}
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("ES256 requires a P-256 private key")
+		}
+		private = key
+	case jwt.SigningMethodEdDSA:
+		key, err := jwt.ParseEdPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse ed25519 private key: %w", err)
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("parse ed25519 private key: unexpected key type")
+		}
+		private = edKey
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm %q", method.Alg())
+	}
+
+	return &SigningKey{Method: method, Private: private, Public: private.Public()}, nil
+}
+
+func GenerateSigningKey(method jwt.SigningMethod) (*SigningKey, []byte, error) {
+	var private interface{}
+	var err error
+
+	switch method {
+	case jwt.SigningMethodHS512:
+		secret := make([]byte, 64)
+		if _, err = rand.Read(secret); err != nil {
+			return nil, nil, fmt.Errorf("generate hmac key: %w", err)
+		}
+		id := make([]byte, 8)
+		if _, err = rand.Read(id); err != nil {
+			return nil, nil, fmt.Errorf("generate key id: %w", err)
+		}
+		key := &SigningKey{ID: hex.EncodeToString(id), Method: method, Private: secret, Public: secret}
+		return key, pem.EncodeToMemory(&pem.Block{Type: hmacKeyPEMType, Bytes: secret}), nil
+	case jwt.SigningMethodRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case jwt.SigningMethodEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, nil, fmt.Errorf("unsupported jwt algorithm %q", method.Alg())
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate %s key: %w", method.Alg(), err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal private key: %w", err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	key, err := ParsePrivateKey(method, pemBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key.ID, err = Thumbprint(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return key, pemBytes, nil
+}
+
+// Thumbprint returns the RFC 7638 thumbprint of the public key, used as its kid.
+func Thumbprint(key *SigningKey) (string, error) {
+	jwk, err := PublicJWK(key)
+	if err != nil {
+		return "", err
+	}
+
+	var members interface{}
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", fmt.Errorf("marshal jwk thumbprint members: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	return encodeBase64URL(sum[:]), nil
+}
+
+func (k *SigningKey) IsAsymmetric() bool {
+	switch k.Public.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return true
+	default:
+		return false
+	}
+}
+
+func (k *SigningKey) IsRetired(now time.Time) bool {
+	return !k.RetiredAt.IsZero() && !now.Before(k.RetiredAt)
+}
+
+// CanVerify reports whether tokens signed with the key are still accepted:
+// retired keys stay valid for verification until their grace period ends.
+func (k *SigningKey) CanVerify(now time.Time, grace time.Duration) bool {
+	return k.RetiredAt.IsZero() || now.Before(k.RetiredAt.Add(grace))
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const mfaPendingType = "mfa_pending"
+
+// recoveryCodeAlphabet is Crockford's base32 alphabet: it leaves out letters
+// that are easy to confuse when a code is typed from paper.
+const recoveryCodeAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+
+// GenerateMFAToken signs a short lived token proving that the password step of
+// a login succeeded. It carries no token_pair_id and is therefore never
+// accepted as an access token. Its jti identifies it to the attempt counter.
+func GenerateMFAToken(userID string, amr []string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"jti":     uuid.NewString(),
+		"typ":     mfaPendingType,
+		"user_id": userID,
+		"amr":     amr,
+		"iat":     now.Unix(),
+		"exp":     now.Add(ttl).Unix(),
+	}
+
+	return signClaims(claims)
+}
+
+// MFAPending is the content of an mfa_pending token.
+type MFAPending struct {
+	ID     string
+	UserID string
+	AMR    []string
+}
+
+func ParseMFAToken(tokenString string) (MFAPending, error) {
+	claims, err := parseToken(tokenString)
+	if err != nil {
+		return MFAPending{}, err
+	}
+
+	if typ, _ := claims["typ"].(string); typ != mfaPendingType {
+		return MFAPending{}, fmt.Errorf("token is not an mfa_pending token")
+	}
+
+	id, ok := claims["jti"].(string)
+	if !ok || id == "" {
+		return MFAPending{}, fmt.Errorf("jti is missing in token claims")
+	}
+
+	userID, ok := claims["user_id"].(string)
+	if !ok || userID == "" {
+		return MFAPending{}, fmt.Errorf("user_id is missing in token claims")
+	}
+
+	return MFAPending{ID: id, UserID: userID, AMR: ClaimStrings(claims, "amr")}, nil
+}
+
+// ClaimStrings reads a claim holding a JSON array of strings.
+func ClaimStrings(claims jwt.MapClaims, name string) []string {
+	values, _ := claims[name].([]interface{})
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// GenerateRecoveryCodes returns n codes formatted as "xxxxx-xxxxx".
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	buf := make([]byte, 10)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("generate recovery code: %w", err)
+		}
+
+		var code strings.Builder
+		for j, b := range buf {
+			if j == 5 {
+				code.WriteByte('-')
+			}
+			code.WriteByte(recoveryCodeAlphabet[b&31])
+		}
+		codes[i] = code.String()
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode hashes a recovery code the way it is stored. Case,
+// whitespace and dashes are ignored, and the letters Crockford's base32 reads
+// as digits are mapped to them, so a code typed from paper still matches.
+func HashRecoveryCode(key []byte, code string) string {
+	code = strings.Map(func(r rune) rune {
+		switch {
+		case r == '-' || unicode.IsSpace(r):
+			return -1
+		case r == 'o' || r == 'O':
+			return '0'
+		case r == 'i' || r == 'I' || r == 'l' || r == 'L':
+			return '1'
+		}
+		return unicode.ToLower(r)
+	}, code)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
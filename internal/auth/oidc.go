@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"auth-service/models"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"hash"
+	"time"
+)
+
+// ErrSymmetricKey is returned while the active key is an HMAC secret. Relying
+// parties could verify ID tokens signed with it only by knowing SECRET_KEY,
+// which would let them mint access tokens too.
+var ErrSymmetricKey = errors.New("id tokens require an asymmetric signing key")
+
+// SigningAlgorithm returns the alg of the active key, which ID tokens are
+// signed with.
+func SigningAlgorithm() (string, error) {
+	key, err := idTokenKey()
+	if err != nil {
+		return "", err
+	}
+	return key.Method.Alg(), nil
+}
+
+// GenerateIDToken issues an OpenID Connect ID token for the client of the
+// grant. at_hash binds it to the access token issued alongside.
+func GenerateIDToken(issuer string, grant models.Grant, accessToken string, ttl time.Duration) (string, error) {
+	key, err := idTokenKey()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":       issuer,
+		"sub":       grant.UserID,
+		"aud":       grant.ClientID,
+		"azp":       grant.ClientID,
+		"auth_time": grant.AuthTime.Unix(),
+		"at_hash":   tokenHash(key.Method.Alg(), accessToken),
+		"iat":       now.Unix(),
+		"exp":       now.Add(ttl).Unix(),
+	}
+	if grant.Nonce != "" {
+		claims["nonce"] = grant.Nonce
+	}
+	if len(grant.AMR) > 0 {
+		claims["amr"] = grant.AMR
+	}
+
+	return signClaimsWith(key, claims)
+}
+
+func idTokenKey() (*SigningKey, error) {
+	kr, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	key := kr.Active()
+	if _, ok := key.Method.(*jwt.SigningMethodHMAC); ok {
+		return nil, ErrSymmetricKey
+	}
+	return key, nil
+}
+
+// tokenHash computes at_hash: the left half of the token hash, with the hash
+// function of the signing algorithm (OpenID Connect Core section 3.1.3.6).
+func tokenHash(alg, token string) string {
+	var h hash.Hash
+	switch alg {
+	case jwt.SigningMethodHS512.Alg(), jwt.SigningMethodEdDSA.Alg():
+		h = sha512.New()
+	default:
+		h = sha256.New()
+	}
+	h.Write([]byte(token))
+	sum := h.Sum(nil)
+	return encodeBase64URL(sum[:len(sum)/2])
+}
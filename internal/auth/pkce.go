@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+// pkceValuePattern matches both a code verifier and an S256 challenge:
+// 43 to 128 unreserved characters as defined in RFC 7636 section 4.1.
+var pkceValuePattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+func ValidPKCEValue(value string) bool {
+	return pkceValuePattern.MatchString(value)
+}
+
+// VerifyPKCE checks a code_verifier against an S256 code_challenge.
+func VerifyPKCE(verifier, challenge string) bool {
+	if !ValidPKCEValue(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// GenerateAuthorizationCode returns a code and the hash it is stored under.
+// The code has 256 bits of entropy, so an unkeyed hash is enough.
+func GenerateAuthorizationCode() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("generate authorization code: %w", err)
+	}
+	code := base64.RawURLEncoding.EncodeToString(raw)
+	return code, HashAuthorizationCode(code), nil
+}
+
+func HashAuthorizationCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+const (
+	refreshSelectorSize = 16
+	refreshVerifierSize = 32
+)
+
+// GenerateRefreshToken returns a "selector.verifier" refresh token together
+// with its selector, used as an indexed lookup key, and the keyed hash of
+// the verifier that is stored instead of the verifier itself.
+func GenerateRefreshToken(hashKey []byte) (string, string, string, error) {
+	selectorBytes := make([]byte, refreshSelectorSize)
+	if _, err := rand.Read(selectorBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate refresh token selector: %w", err)
+	}
+
+	verifierBytes := make([]byte, refreshVerifierSize)
+	if _, err := rand.Read(verifierBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate refresh token verifier: %w", err)
+	}
+
+	selector := base64.RawURLEncoding.EncodeToString(selectorBytes)
+	verifier := base64.RawURLEncoding.EncodeToString(verifierBytes)
+
+	return selector + "." + verifier, selector, HashRefreshVerifier(hashKey, verifierBytes), nil
+}
+
+// SplitRefreshToken parses a "selector.verifier" refresh token. Tokens issued
+// before this format existed are reported with ok == false.
+func SplitRefreshToken(refresh string) (string, []byte, bool) {
+	selector, verifier, found := strings.Cut(refresh, ".")
+	if !found {
+		return "", nil, false
+	}
+
+	selectorBytes, err := base64.RawURLEncoding.DecodeString(selector)
+	if err != nil || len(selectorBytes) != refreshSelectorSize {
+		return "", nil, false
+	}
+
+	verifierBytes, err := base64.RawURLEncoding.DecodeString(verifier)
+	if err != nil || len(verifierBytes) != refreshVerifierSize {
+		return "", nil, false
+	}
+
+	return selector, verifierBytes, true
+}
+
+func HashRefreshVerifier(hashKey, verifier []byte) string {
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write(verifier)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func VerifyRefreshVerifier(hashKey, verifier []byte, storedHash string) bool {
+	expected, err := hex.DecodeString(storedHash)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write(verifier)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// RefreshTokenLookup returns the indexed key of a legacy refresh token.
+func RefreshTokenLookup(refresh string) (string, error) {
+	tokenBytes, err := base64.URLEncoding.DecodeString(refresh)
+	if err != nil {
+		return "", fmt.Errorf("decode refresh token: %w", err)
+	}
+
+	sum := sha256.Sum256(tokenBytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyLegacyRefreshToken checks a refresh token issued before the
+// selector/verifier format against its bcrypt hash.
+func VerifyLegacyRefreshToken(refresh, bcryptHash string) error {
+	tokenBytes, err := base64.URLEncoding.DecodeString(refresh)
+	if err != nil {
+		return fmt.Errorf("decode refresh token: %w", err)
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(bcryptHash), tokenBytes)
+}
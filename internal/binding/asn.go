@@ -0,0 +1,90 @@
+package binding
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type asnRange struct {
+	network netip.Prefix
+	asn     uint32
+}
+
+// ASNTable maps networks to the autonomous systems announcing them.
+type ASNTable struct {
+	ranges []asnRange
+}
+
+// LoadASNTable reads a "network,asn[,organization]" CSV file such as the
+// GeoLite2 ASN blocks. A header row and lines starting with # are skipped.
+func LoadASNTable(path string) (*ASNTable, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	table := &ASNTable{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read asn database: %w", err)
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected network and asn", line)
+		}
+
+		network, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		asn, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(record[1]), "AS"), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid asn %q", line, record[1])
+		}
+
+		table.ranges = append(table.ranges, asnRange{network: network.Masked(), asn: uint32(asn)})
+	}
+
+	slices.SortFunc(table.ranges, func(a, b asnRange) int {
+		return a.network.Addr().Compare(b.network.Addr())
+	})
+
+	return table, nil
+}
+
+// Lookup returns the ASN of the network with the greatest start address not
+// after addr. Networks of the table are expected not to overlap.
+func (t *ASNTable) Lookup(addr netip.Addr) (uint32, bool) {
+	i, found := slices.BinarySearchFunc(t.ranges, addr, func(r asnRange, addr netip.Addr) int {
+		return r.network.Addr().Compare(addr)
+	})
+	if !found {
+		i--
+	}
+	if i < 0 || !t.ranges[i].network.Contains(addr) {
+		return 0, false
+	}
+	return t.ranges[i].asn, true
+}
+
+func (t *ASNTable) Len() int {
+	return len(t.ranges)
+}
@@ -0,0 +1,168 @@
+package binding
+
+import (
+	"auth-service/config"
+	"fmt"
+)
+
+// Modes of the user agent check.
+const (
+	UserAgentStrict = "strict"
+	UserAgentFamily = "family"
+	UserAgentIgnore = "ignore"
+)
+
+// Modes of the IP check.
+const (
+	IPExact  = "exact"
+	IPSubnet = "subnet"
+	IPASN    = "asn"
+	IPIgnore = "ignore"
+)
+
+// Actions taken when a check fails, from the mildest to the strictest.
+const (
+	ActionNotify = "notify"
+	ActionStepUp = "step_up"
+	ActionDeny   = "deny"
+)
+
+// Names of the built-in checks.
+const (
+	CheckUserAgent = "user_agent"
+	CheckIP        = "ip"
+)
+
+var actionSeverity = map[string]int{
+	ActionNotify: 1,
+	ActionStepUp: 2,
+	ActionDeny:   3,
+}
+
+// Client is what a session is bound to: the user agent and IP its refresh
+// token was issued to, or the ones presenting it.
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+// Checker compares the client a session is bound to with the current one.
+type Checker interface {
+	Name() string
+	Match(bound, current Client) bool
+}
+
+type Rule struct {
+	Checker Checker
+	Action  string
+}
+
+type Violation struct {
+	Check  string
+	Action string
+}
+
+// Result lists the failed checks. Action is the strictest action among them,
+// empty when every check passed.
+type Result struct {
+	Action     string
+	Violations []Violation
+}
+
+type Policy struct {
+	rules []Rule
+}
+
+func New(rules ...Rule) *Policy {
+	return &Policy{rules: rules}
+}
+
+// NewPolicy builds the policy configured by the BINDING_* variables.
+func NewPolicy(cfg config.Binding) (*Policy, error) {
+	var rules []Rule
+
+	uaChecker, err := userAgentChecker(cfg.UserAgent)
+	if err != nil {
+		return nil, err
+	}
+	if uaChecker != nil {
+		if err = validateAction(cfg.UserAgentAction); err != nil {
+			return nil, fmt.Errorf("user agent: %w", err)
+		}
+		rules = append(rules, Rule{Checker: uaChecker, Action: cfg.UserAgentAction})
+	}
+
+	ipChecker, err := ipChecker(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if ipChecker != nil {
+		if err = validateAction(cfg.IPAction); err != nil {
+			return nil, fmt.Errorf("ip: %w", err)
+		}
+		rules = append(rules, Rule{Checker: ipChecker, Action: cfg.IPAction})
+	}
+
+	return New(rules...), nil
+}
+
+func (p *Policy) Evaluate(bound, current Client) Result {
+	var result Result
+	for _, rule := range p.rules {
+		if rule.Checker.Match(bound, current) {
+			continue
+		}
+		result.Violations = append(result.Violations, Violation{Check: rule.Checker.Name(), Action: rule.Action})
+		if actionSeverity[rule.Action] > actionSeverity[result.Action] {
+			result.Action = rule.Action
+		}
+	}
+	return result
+}
+
+func userAgentChecker(mode string) (Checker, error) {
+	switch mode {
+	case UserAgentStrict:
+		return UserAgentExactChecker{}, nil
+	case UserAgentFamily:
+		return UserAgentFamilyChecker{}, nil
+	case UserAgentIgnore:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown user agent binding mode %q", mode)
+	}
+}
+
+func ipChecker(cfg config.Binding) (Checker, error) {
+	subnet, err := NewSubnetChecker(cfg.IPv4PrefixLen, cfg.IPv6PrefixLen)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.IP {
+	case IPExact:
+		return IPExactChecker{}, nil
+	case IPSubnet:
+		return subnet, nil
+	case IPASN:
+		if cfg.ASNDatabase == "" {
+			return nil, fmt.Errorf("ip binding mode %q requires BINDING_ASN_DATABASE", IPASN)
+		}
+		table, err := LoadASNTable(cfg.ASNDatabase)
+		if err != nil {
+			return nil, fmt.Errorf("load asn database: %w", err)
+		}
+		return ASNChecker{Table: table, Fallback: subnet}, nil
+	case IPIgnore:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown ip binding mode %q", cfg.IP)
+	}
+}
+
+func validateAction(action string) error {
+	if _, ok := actionSeverity[action]; !ok {
+		return fmt.Errorf("unknown binding action %q", action)
+	}
+	return nil
+}
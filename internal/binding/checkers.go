@@ -0,0 +1,116 @@
+package binding
+
+import (
+	"auth-service/internal/useragent"
+	"fmt"
+	"net/netip"
+)
+
+// UserAgentExactChecker requires the very same User-Agent string, so any
+// browser update ends the session.
+type UserAgentExactChecker struct{}
+
+func (UserAgentExactChecker) Name() string { return CheckUserAgent }
+
+func (UserAgentExactChecker) Match(bound, current Client) bool {
+	return bound.UserAgent == current.UserAgent
+}
+
+// UserAgentFamilyChecker requires the same browser and OS regardless of their
+// versions. Unrecognized user agents are compared exactly.
+type UserAgentFamilyChecker struct{}
+
+func (UserAgentFamilyChecker) Name() string { return CheckUserAgent }
+
+func (UserAgentFamilyChecker) Match(bound, current Client) bool {
+	if bound.UserAgent == current.UserAgent {
+		return true
+	}
+
+	boundUA, currentUA := useragent.Parse(bound.UserAgent), useragent.Parse(current.UserAgent)
+	if boundUA.Browser == useragent.UnknownBrowser || currentUA.Browser == useragent.UnknownBrowser {
+		return false
+	}
+	return boundUA.Family() == currentUA.Family()
+}
+
+type IPExactChecker struct{}
+
+func (IPExactChecker) Name() string { return CheckIP }
+
+func (IPExactChecker) Match(bound, current Client) bool {
+	boundIP, err1 := parseIP(bound.IP)
+	currentIP, err2 := parseIP(current.IP)
+	if err1 != nil || err2 != nil {
+		return bound.IP == current.IP
+	}
+	return boundIP == currentIP
+}
+
+// SubnetChecker requires both addresses to be in the same network of the
+// configured size, /24 and /48 by default.
+type SubnetChecker struct {
+	ipv4Bits int
+	ipv6Bits int
+}
+
+func NewSubnetChecker(ipv4Bits, ipv6Bits int) (SubnetChecker, error) {
+	if ipv4Bits < 0 || ipv4Bits > 32 {
+		return SubnetChecker{}, fmt.Errorf("invalid ipv4 prefix length %d", ipv4Bits)
+	}
+	if ipv6Bits < 0 || ipv6Bits > 128 {
+		return SubnetChecker{}, fmt.Errorf("invalid ipv6 prefix length %d", ipv6Bits)
+	}
+	return SubnetChecker{ipv4Bits: ipv4Bits, ipv6Bits: ipv6Bits}, nil
+}
+
+func (SubnetChecker) Name() string { return CheckIP }
+
+func (c SubnetChecker) Match(bound, current Client) bool {
+	boundIP, err1 := parseIP(bound.IP)
+	currentIP, err2 := parseIP(current.IP)
+	if err1 != nil || err2 != nil {
+		return bound.IP == current.IP
+	}
+	if boundIP.Is4() != currentIP.Is4() {
+		return false
+	}
+
+	bits := c.ipv6Bits
+	if boundIP.Is4() {
+		bits = c.ipv4Bits
+	}
+	boundNet, _ := boundIP.Prefix(bits)
+	currentNet, _ := currentIP.Prefix(bits)
+	return boundNet == currentNet
+}
+
+// ASNChecker requires both addresses to be announced by the same autonomous
+// system. Addresses missing from the table are compared by Fallback.
+type ASNChecker struct {
+	Table    *ASNTable
+	Fallback Checker
+}
+
+func (ASNChecker) Name() string { return CheckIP }
+
+func (c ASNChecker) Match(bound, current Client) bool {
+	boundIP, err1 := parseIP(bound.IP)
+	currentIP, err2 := parseIP(current.IP)
+	if err1 == nil && err2 == nil {
+		boundASN, ok1 := c.Table.Lookup(boundIP)
+		currentASN, ok2 := c.Table.Lookup(currentIP)
+		if ok1 && ok2 {
+			return boundASN == currentASN
+		}
+	}
+	return c.Fallback.Match(bound, current)
+}
+
+func parseIP(value string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap().WithZone(""), nil
+}
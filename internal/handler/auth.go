@@ -7,51 +7,52 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/google/uuid"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // generateTokensHandler godoc
-// @Summary Генерация access и refresh токенов
-// @Description Генерирует пару токенов для пользователя
+// @Summary Токен эндпоинт OAuth 2.1
+// @Description С grant_type=authorization_code обменивает код авторизации на токены по OAuth 2.1 с проверкой PKCE. С grant_type=refresh_token обновляет токены сессии клиента по refresh токену, выданному этому клиенту (конфиденциальный клиент передает секрет, публичный — client_id); refresh токен ротируется. С grant_type=client_credentials выдает клиенту access токен без refresh токена (sub = client_id). Пользователи получают токены через /login, /login/mfa и /webauthn/login/finish
 // @Tags auth
-// @Accept json
+// @Accept x-www-form-urlencoded
 // @Produce json
-// @Param user_id query string true "ID пользователя"
-// @Success 200 {object} models.TokensResponse "Успешный ответ"
-// @Failure 400 {object} models.Error "Некорректный запрос"
-// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Param grant_type formData string true "authorization_code, refresh_token или client_credentials"
+// @Param code formData string false "Код авторизации"
+// @Param redirect_uri formData string false "redirect_uri из запроса авторизации"
+// @Param client_id formData string false "ID клиента (или HTTP Basic)"
+// @Param client_secret formData string false "Секрет конфиденциального клиента (или HTTP Basic)"
+// @Param code_verifier formData string false "PKCE code_verifier"
+// @Param refresh_token formData string false "Refresh токен для grant_type=refresh_token"
+// @Param scope formData string false "Запрашиваемые scope для client_credentials"
+// @Success 200 {object} models.OAuthTokenResponse "Успешный ответ (со scope openid в нем есть id_token)"
+// @Failure 400 {object} models.OAuthError "Некорректный запрос"
+// @Failure 401 {object} models.OAuthError "Ошибка аутентификации клиента"
+// @Failure 429 {object} models.Error "Превышен лимит запросов"
+// @Failure 500 {object} models.OAuthError "Внутренняя ошибка сервера"
 // @Router /token [post]
-// @Example request {"user_id": "b3b3b3b3-b3b3-b3b3-b3b3-b3b3b3b3b3b3"}
-// @Example success {"access": "eyJhbGciOiJIUzI1NiIsInR5cCI6...", "refresh": "eyJhbGciOiJIUzI1NiIsInR5cCI6..."}
-// @Example error {"message": "user_id обязателен"}
+// @Example error {"error": "invalid_request", "error_description": "grant_type is required"}
 func (h Handler) generateTokensHandler(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	if userID == "" {
-		utils.WriteError(w, http.StatusBadRequest, "user_id обязателен")
-		return
-	}
-
-	if _, err := uuid.Parse(userID); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "неверный формат user_id")
-		return
+	switch grantType := r.PostFormValue("grant_type"); grantType {
+	case "":
+		utils.WriteOAuthError(w, http.StatusBadRequest, apperrors.OAuthInvalidRequest, "grant_type is required")
+	case models.GrantAuthorizationCode:
+		h.authorizationCodeGrant(w, r)
+	case models.GrantClientCredentials:
+		h.clientCredentialsGrant(w, r)
+	case models.GrantRefreshToken:
+		h.refreshTokenGrant(w, r)
+	default:
+		utils.WriteOAuthError(w, http.StatusBadRequest, apperrors.OAuthUnsupportedGrantType, "unsupported grant_type")
 	}
-
-	ip := utils.GetIP(r)
-	userAgent := r.UserAgent()
-
-	resp, err := h.service.GenerateTokens(r.Context(), userID, ip, userAgent)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "ошибка генерации токенов")
-		return
-	}
-
-	utils.SendJSON(w, http.StatusOK, resp)
 }
 
 // refreshTokensHandler godoc
 // @Summary Обновление access и refresh токенов
-// @Description Обновляет пару токенов по refresh токену
+// @Description Обновляет пару токенов по refresh токену. Access токен может быть просрочен, но не старше JWT_REFRESH_ACCESS_MAX_AGE
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -59,6 +60,7 @@ func (h Handler) generateTokensHandler(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} models.TokensResponse "Успешный ответ"
 // @Failure 400 {object} models.Error "Некорректный запрос"
 // @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 429 {object} models.Error "Превышен лимит запросов или refresh временно заблокирован после неверных токенов"
 // @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
 // @Router /token/refresh [post]
 // @Example request {"user_id": "b3b3b3b3-b3b3-b3b3-b3b3-b3b3b3b3b3b3", "access": "...", "refresh": "..."}
@@ -86,17 +88,34 @@ func (h Handler) refreshTokensHandler(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := h.service.RefreshTokens(r.Context(), req.UserID, req.Access, req.Refresh, userAgent, ip)
 	if err != nil {
+		var lockout *apperrors.LockoutError
+		var rateLimit *apperrors.RateLimitError
 		switch {
+		case errors.As(err, &rateLimit):
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(rateLimit.Limit))
+			w.Header().Set("RateLimit-Remaining", "0")
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(rateLimit.Reset.Seconds()))))
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(rateLimit.RetryAfter.Seconds())), 1)))
+			utils.WriteError(w, http.StatusTooManyRequests, "слишком много запросов, повторите позже")
+		case errors.As(err, &lockout):
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(time.Until(lockout.Until).Seconds())), 1)))
+			utils.WriteError(w, http.StatusTooManyRequests, "слишком много неверных refresh токенов, повторите позже")
 		case errors.Is(err, apperrors.ErrTokenExpired):
 			utils.WriteError(w, http.StatusUnauthorized, "срок действия токена истек")
+		case errors.Is(err, apperrors.ErrRefreshExpired):
+			utils.WriteError(w, http.StatusUnauthorized, "срок действия refresh токена истек, требуется повторный вход")
 		case errors.Is(err, apperrors.ErrInvalidToken):
 			utils.WriteError(w, http.StatusUnauthorized, "недействительный токен")
+		case errors.Is(err, apperrors.ErrTokenReused):
+			utils.WriteError(w, http.StatusUnauthorized, "повторное использование токена, все сессии цепочки отозваны")
 		case errors.Is(err, apperrors.ErrTokenRevoked):
 			utils.WriteError(w, http.StatusUnauthorized, "токен отозван")
 		case errors.Is(err, apperrors.ErrTokenIsNotFound):
 			utils.WriteError(w, http.StatusUnauthorized, "токен не найден")
 		case errors.Is(err, apperrors.ErrUserDeauthorized):
 			utils.WriteError(w, http.StatusUnauthorized, "пользователь деавторизован")
+		case errors.Is(err, apperrors.ErrStepUpRequired):
+			utils.WriteError(w, http.StatusUnauthorized, "требуется повторный вход")
 		case errors.Is(err, apperrors.ErrAlreadyLoggedOut):
 			utils.WriteError(w, http.StatusUnauthorized, "пользователь уже деавторизован")
 		default:
@@ -116,7 +135,6 @@ func (h Handler) refreshTokensHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Success 200 {object} map[string]string "Успешный ответ"
 // @Failure 401 {object} models.Error "Ошибка авторизации"
-// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
 // @Router /me [get]
 // @Security BearerAuth
 // @Example success {"user_id": "b3b3b3b3-b3b3-b3b3-b3b3-b3b3b3b3b3b3"}
@@ -128,34 +146,6 @@ func (h Handler) meHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, ok := r.Context().Value("access_token").(string)
-	if !ok || accessToken == "" {
-		utils.WriteError(w, http.StatusUnauthorized, "access токен не найден")
-		return
-	}
-
-	claims, err := h.service.ParseAccessTokenClaims(accessToken)
-	if err != nil {
-		utils.WriteError(w, http.StatusUnauthorized, "невалидный access токен")
-		return
-	}
-
-	pairID, ok := claims["token_pair_id"].(string)
-	if !ok || pairID == "" {
-		utils.WriteError(w, http.StatusUnauthorized, "token_pair_id отсутствует в токене")
-		return
-	}
-
-	revoked, err := h.service.IsRefreshTokenRevoked(r.Context(), userID, pairID)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "ошибка проверки статуса токена")
-		return
-	}
-	if revoked {
-		utils.WriteError(w, http.StatusUnauthorized, "пользователь деавторизован")
-		return
-	}
-
 	utils.SendJSON(w, http.StatusOK, map[string]string{"user_id": userID})
 }
 
@@ -167,6 +157,7 @@ func (h Handler) meHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Success 200 {object} nil "Успешный ответ"
 // @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 429 {object} models.Error "Превышен лимит запросов"
 // @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
 // @Router /logout [post]
 // @Security BearerAuth
@@ -2,7 +2,9 @@ package handler
 
 import (
 	"auth-service/internal/middleware"
+	"auth-service/internal/ratelimit"
 	"auth-service/internal/service"
+	"auth-service/models"
 	"github.com/go-chi/chi/v5"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"net/http"
@@ -14,11 +16,13 @@ type HandlerI interface {
 
 type Handler struct {
 	service service.ServiceI
+	limiter *ratelimit.Limiter
 }
 
-func NewHandler(service service.ServiceI) HandlerI {
+func NewHandler(service service.ServiceI, limiter *ratelimit.Limiter) HandlerI {
 	return &Handler{
 		service: service,
+		limiter: limiter,
 	}
 }
 
@@ -26,15 +30,61 @@ func (h Handler) NewRouter() http.Handler {
 	r := chi.NewRouter()
 
 	r.Get("/swagger/*", h.swaggerHandler())
+	r.Get("/.well-known/jwks.json", h.jwksHandler)
+	r.Get("/.well-known/openid-configuration", h.openIDConfigurationHandler)
 
-	r.Post("/token", h.generateTokensHandler)
-	r.Post("/token/refresh", h.refreshTokensHandler)
+	r.Post("/register", h.registerHandler)
+	r.With(middleware.RateLimit(h.limiter, nil)).Post("/login", h.loginHandler)
+	r.With(middleware.RateLimit(h.limiter, nil)).Post("/login/mfa", h.mfaLoginHandler)
+	r.With(middleware.RateLimit(h.limiter, nil)).Post("/webauthn/login/begin", h.webAuthnLoginBeginHandler)
+	r.With(middleware.RateLimit(h.limiter, nil)).Post("/webauthn/login/finish", h.webAuthnLoginFinishHandler)
+	r.With(middleware.RateLimit(h.limiter, nil)).Post("/token", h.generateTokensHandler)
+	r.With(middleware.RateLimit(h.limiter, nil)).Post("/token/refresh", h.refreshTokensHandler)
+	r.Route("/authorize", func(r chi.Router) {
+		r.Use(middleware.FormBearerToken, middleware.AuthMiddleware(h.service))
+
+		r.Get("/", h.authorizeHandler)
+		r.Post("/", h.authorizeHandler)
+	})
+	r.With(middleware.RateLimit(h.limiter, nil)).Post("/introspect", h.introspectHandler)
+	r.With(middleware.RateLimit(h.limiter, nil)).Post("/revoke", h.revokeHandler)
 
 	r.Route("/", func(r chi.Router) {
-		r.Use(middleware.AuthMiddleware())
+		r.Use(middleware.AuthMiddleware(h.service))
 
 		r.Get("/me", h.meHandler)
-		r.Post("/logout", h.logoutHandler)
+		r.With(middleware.RequireScopes("openid")).Get("/userinfo", h.userInfoHandler)
+		r.With(middleware.RequireScopes("openid")).Post("/userinfo", h.userInfoHandler)
+		r.Get("/sessions", h.listSessionsHandler)
+		r.Delete("/sessions/{pair_id}", h.revokeSessionHandler)
+		r.With(middleware.RateLimit(h.limiter, middleware.UserIDFromContext)).Post("/logout", h.logoutHandler)
+		r.Post("/logout/all", h.logoutAllHandler)
+		r.Post("/mfa/totp/enroll", h.enrollTOTPHandler)
+		r.Post("/mfa/totp/confirm", h.confirmTOTPHandler)
+		r.Post("/webauthn/register/begin", h.webAuthnRegisterBeginHandler)
+		r.Post("/webauthn/register/finish", h.webAuthnRegisterFinishHandler)
+
+		r.Route("/admin/roles", func(r chi.Router) {
+			canRead := middleware.RequirePermission(h.service, models.PermissionRolesRead)
+			canWrite := middleware.RequirePermission(h.service, models.PermissionRolesWrite)
+
+			r.With(canRead).Get("/", h.listRolesHandler)
+			r.With(canWrite).Post("/", h.createRoleHandler)
+			r.With(canRead).Get("/{name}", h.getRoleHandler)
+			r.With(canWrite).Put("/{name}", h.updateRoleHandler)
+			r.With(canWrite).Delete("/{name}", h.deleteRoleHandler)
+			r.With(canWrite).Put("/{name}/users/{user_id}", h.assignRoleHandler)
+			r.With(canWrite).Delete("/{name}/users/{user_id}", h.unassignRoleHandler)
+		})
+
+		r.Route("/admin/outbox", func(r chi.Router) {
+			canRead := middleware.RequirePermission(h.service, models.PermissionOutboxRead)
+			canWrite := middleware.RequirePermission(h.service, models.PermissionOutboxWrite)
+
+			r.With(canRead).Get("/", h.listOutboxHandler)
+			r.With(canWrite).Post("/replay", h.replayDeadOutboxHandler)
+			r.With(canWrite).Post("/{id}/replay", h.replayOutboxEventHandler)
+		})
 	})
 
 	return r
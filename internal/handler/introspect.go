@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"auth-service/internal/utils"
+	"net/http"
+)
+
+// introspectHandler godoc
+// @Summary Интроспекция токена (RFC 7662)
+// @Description Возвращает состояние access или refresh токена. Требует аутентификации клиента (HTTP Basic или client_id/client_secret)
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access или refresh токен"
+// @Param token_type_hint formData string false "access_token или refresh_token"
+// @Success 200 {object} models.IntrospectionResponse "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка аутентификации клиента"
+// @Failure 429 {object} models.Error "Превышен лимит запросов"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /introspect [post]
+// @Example success {"active": true, "token_type": "access_token", "sub": "b3b3b3b3-b3b3-b3b3-b3b3-b3b3b3b3b3b3", "exp": 1735689600, "iat": 1735688700, "token_pair_id": "..."}
+// @Example error {"message": "неверные учетные данные клиента"}
+func (h Handler) introspectHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	clientID, clientSecret := clientCredentials(r)
+
+	if err := h.service.AuthenticateClient(r.Context(), clientID, clientSecret); err != nil {
+		w.Header().Set("WWW-Authenticate", `Basic realm="auth-service"`)
+		utils.WriteError(w, http.StatusUnauthorized, "неверные учетные данные клиента")
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		utils.WriteError(w, http.StatusBadRequest, "token обязателен")
+		return
+	}
+
+	resp, err := h.service.Introspect(r.Context(), token, r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка интроспекции токена")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.SendJSON(w, http.StatusOK, resp)
+}
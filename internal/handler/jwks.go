@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"auth-service/internal/utils"
+	"net/http"
+)
+
+// jwksHandler godoc
+// @Summary Публичные ключи подписи
+// @Description Возвращает JWKS с публичными ключами для проверки access токенов
+// @Tags keys
+// @Produce json
+// @Success 200 {object} models.JWKSet "Успешный ответ"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /.well-known/jwks.json [get]
+func (h Handler) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	set, err := h.service.PublicKeys()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка получения ключей")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.SendJSON(w, http.StatusOK, set)
+}
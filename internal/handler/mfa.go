@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/utils"
+	"auth-service/models"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// mfaLoginHandler godoc
+// @Summary Второй шаг входа
+// @Description Завершает вход по mfa_token из POST /login и коду TOTP либо одноразовому коду восстановления
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.MFALoginRequest true "mfa_token и код"
+// @Success 200 {object} models.TokensResponse "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Неверный код или mfa_token"
+// @Failure 429 {object} models.Error "Превышен лимит запросов или вход временно заблокирован после неверных кодов"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /login/mfa [post]
+// @Example request {"mfa_token": "eyJhbGciOiJIUzUxMiIsInR5cCI6...", "code": "123456"}
+// @Example success {"access": "eyJhbGciOiJIUzI1NiIsInR5cCI6...", "refresh": "..."}
+// @Example error {"message": "неверный код"}
+func (h Handler) mfaLoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.MFALoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат JSON")
+		return
+	}
+
+	if req.MFAToken == "" || (req.Code == "") == (req.RecoveryCode == "") {
+		utils.WriteError(w, http.StatusBadRequest, "требуются mfa_token и один из параметров code или recovery_code")
+		return
+	}
+
+	resp, err := h.service.CompleteMFALogin(r.Context(), req, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		var lockout *apperrors.LockoutError
+		switch {
+		case errors.As(err, &lockout):
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(time.Until(lockout.Until).Seconds())), 1)))
+			utils.WriteError(w, http.StatusTooManyRequests, "слишком много неверных кодов, повторите позже")
+		case errors.Is(err, apperrors.ErrInvalidToken), errors.Is(err, apperrors.ErrMFANotEnrolled):
+			utils.WriteError(w, http.StatusUnauthorized, "недействительный mfa_token")
+		case errors.Is(err, apperrors.ErrInvalidMFACode):
+			utils.WriteError(w, http.StatusUnauthorized, "неверный код")
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, "ошибка входа")
+		}
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
+
+// enrollTOTPHandler godoc
+// @Summary Подключение TOTP
+// @Description Создает новый секрет TOTP и otpauth:// URI для приложения-аутентификатора. Второй фактор включается только после подтверждения кодом
+// @Tags mfa
+// @Produce json
+// @Success 200 {object} models.TOTPEnrollResponse "Успешный ответ"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 404 {object} models.Error "Пользователь не найден"
+// @Failure 409 {object} models.Error "TOTP уже подключен"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /mfa/totp/enroll [post]
+// @Security BearerAuth
+// @Example success {"secret": "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP", "otpauth_uri": "otpauth://totp/auth-service:user%40example.com?..."}
+// @Example error {"message": "TOTP уже подключен"}
+func (h Handler) enrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		utils.WriteError(w, http.StatusUnauthorized, "пользователь не авторизован")
+		return
+	}
+
+	resp, err := h.service.EnrollTOTP(r.Context(), userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrUserNotFound):
+			utils.WriteError(w, http.StatusNotFound, "пользователь не найден")
+		case errors.Is(err, apperrors.ErrMFAAlreadyEnabled):
+			utils.WriteError(w, http.StatusConflict, "TOTP уже подключен")
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, "ошибка подключения TOTP")
+		}
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
+
+// confirmTOTPHandler godoc
+// @Summary Подтверждение TOTP
+// @Description Включает второй фактор после проверки первого кода и возвращает коды восстановления. Коды показываются только один раз
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param data body models.TOTPConfirmRequest true "Код из приложения"
+// @Success 200 {object} models.RecoveryCodesResponse "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 404 {object} models.Error "TOTP не подключен"
+// @Failure 409 {object} models.Error "TOTP уже подключен"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /mfa/totp/confirm [post]
+// @Security BearerAuth
+// @Example request {"code": "123456"}
+// @Example success {"recovery_codes": ["4k7pz-qm2xa", "..."]}
+// @Example error {"message": "неверный код"}
+func (h Handler) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		utils.WriteError(w, http.StatusUnauthorized, "пользователь не авторизован")
+		return
+	}
+
+	var req models.TOTPConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат JSON")
+		return
+	}
+
+	if req.Code == "" {
+		utils.WriteError(w, http.StatusBadRequest, "code обязателен")
+		return
+	}
+
+	resp, err := h.service.ConfirmTOTP(r.Context(), userID, req.Code, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrInvalidMFACode):
+			utils.WriteError(w, http.StatusBadRequest, "неверный код")
+		case errors.Is(err, apperrors.ErrMFANotEnrolled):
+			utils.WriteError(w, http.StatusNotFound, "TOTP не подключен")
+		case errors.Is(err, apperrors.ErrMFAAlreadyEnabled):
+			utils.WriteError(w, http.StatusConflict, "TOTP уже подключен")
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, "ошибка подтверждения TOTP")
+		}
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
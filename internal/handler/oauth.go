@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/utils"
+	"auth-service/models"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// authorizeHandler godoc
+// @Summary Авторизация OAuth 2.1
+// @Description Выдает одноразовый код авторизации для сессии текущего access токена и перенаправляет на зарегистрированный redirect_uri с параметрами code и state. PKCE с методом S256 обязателен. Браузер не может передать заголовок Authorization при переходе, поэтому страница отправляет форму POST /authorize с полем access_token и теми же параметрами. Сессия без amr, старше max_age или, при prompt=login, старше OAUTH_PROMPT_LOGIN_MAX_AGE отклоняется с login_required
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Param response_type query string true "Только code"
+// @Param client_id query string true "ID зарегистрированного клиента"
+// @Param redirect_uri query string false "Зарегистрированный redirect URI (можно опустить, если он у клиента один)"
+// @Param scope query string false "Запрашиваемые scope (по умолчанию все scope клиента)"
+// @Param state query string false "Значение, возвращаемое клиенту без изменений"
+// @Param code_challenge query string true "BASE64URL(SHA256(code_verifier))"
+// @Param code_challenge_method query string true "Только S256"
+// @Param nonce query string false "Значение OpenID Connect, возвращаемое в claim nonce ID токена"
+// @Param prompt query string false "none или login"
+// @Param max_age query int false "Максимальный возраст входа пользователя в секундах"
+// @Param access_token formData string false "Access токен для POST из браузера вместо заголовка Authorization"
+// @Success 302 "Перенаправление на redirect_uri с code или error"
+// @Failure 400 {object} models.OAuthError "Неизвестный клиент или redirect_uri"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Router /authorize [get]
+// @Router /authorize [post]
+// @Security BearerAuth
+// @Example error {"error": "invalid_request", "error_description": "redirect_uri is not registered for the client"}
+func (h Handler) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		utils.WriteError(w, http.StatusUnauthorized, "пользователь не авторизован")
+		return
+	}
+	pairID, _ := r.Context().Value("token_pair_id").(string)
+
+	// FormValue reads the query of GET and the form of POST alike.
+	req := models.AuthorizeRequest{
+		ResponseType:        r.FormValue("response_type"),
+		ClientID:            r.FormValue("client_id"),
+		RedirectURI:         r.FormValue("redirect_uri"),
+		Scope:               r.FormValue("scope"),
+		State:               r.FormValue("state"),
+		CodeChallenge:       r.FormValue("code_challenge"),
+		CodeChallengeMethod: r.FormValue("code_challenge_method"),
+		Nonce:               r.FormValue("nonce"),
+		Prompt:              r.FormValue("prompt"),
+		MaxAge:              r.FormValue("max_age"),
+	}
+
+	redirectURI, err := h.service.ResolveRedirectURI(r.Context(), req.ClientID, req.RedirectURI)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrInvalidClient):
+			utils.WriteOAuthError(w, http.StatusBadRequest, apperrors.OAuthInvalidRequest, "unknown client_id")
+		case errors.Is(err, apperrors.ErrInvalidRedirectURI):
+			utils.WriteOAuthError(w, http.StatusBadRequest, apperrors.OAuthInvalidRequest, "redirect_uri is not registered for the client")
+		default:
+			utils.WriteOAuthError(w, http.StatusInternalServerError, apperrors.OAuthServerError, "")
+		}
+		return
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	code, err := h.service.Authorize(r.Context(), userID, pairID, req)
+	if err != nil {
+		var oauthErr *apperrors.OAuthError
+		if errors.As(err, &oauthErr) {
+			params.Set("error", oauthErr.Code)
+			params.Set("error_description", oauthErr.Description)
+		} else {
+			params.Set("error", apperrors.OAuthServerError)
+		}
+	} else {
+		params.Set("code", code)
+	}
+
+	http.Redirect(w, r, withQuery(redirectURI, params), http.StatusFound)
+}
+
+func (h Handler) authorizationCodeGrant(w http.ResponseWriter, r *http.Request) {
+	req := models.TokenRequest{
+		GrantType:    r.PostFormValue("grant_type"),
+		Code:         r.PostFormValue("code"),
+		RedirectURI:  r.PostFormValue("redirect_uri"),
+		CodeVerifier: r.PostFormValue("code_verifier"),
+	}
+	req.ClientID, req.ClientSecret = clientCredentials(r)
+
+	resp, err := h.service.ExchangeAuthorizationCode(r.Context(), req, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		writeOAuthServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.SendJSON(w, http.StatusOK, resp)
+}
+
+func (h Handler) refreshTokenGrant(w http.ResponseWriter, r *http.Request) {
+	req := models.TokenRequest{
+		GrantType:    r.PostFormValue("grant_type"),
+		RefreshToken: r.PostFormValue("refresh_token"),
+	}
+	req.ClientID, req.ClientSecret = clientCredentials(r)
+
+	resp, err := h.service.RefreshTokenGrant(r.Context(), req, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		writeOAuthServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.SendJSON(w, http.StatusOK, resp)
+}
+
+func (h Handler) clientCredentialsGrant(w http.ResponseWriter, r *http.Request) {
+	req := models.TokenRequest{
+		GrantType: r.PostFormValue("grant_type"),
+		Scope:     r.PostFormValue("scope"),
+	}
+	req.ClientID, req.ClientSecret = clientCredentials(r)
+
+	resp, err := h.service.ClientCredentialsGrant(r.Context(), req)
+	if err != nil {
+		writeOAuthServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.SendJSON(w, http.StatusOK, resp)
+}
+
+// clientCredentials returns the client credentials from HTTP Basic or, as
+// RFC 6749 section 2.3.1 also allows, from the form body.
+func clientCredentials(r *http.Request) (clientID, clientSecret string) {
+	if id, secret, ok := r.BasicAuth(); ok {
+		return id, secret
+	}
+	return r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+}
+
+func writeOAuthServiceError(w http.ResponseWriter, err error) {
+	var oauthErr *apperrors.OAuthError
+	if !errors.As(err, &oauthErr) {
+		utils.WriteOAuthError(w, http.StatusInternalServerError, apperrors.OAuthServerError, "")
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == apperrors.OAuthInvalidClient {
+		status = http.StatusUnauthorized
+	}
+	utils.WriteOAuthError(w, status, oauthErr.Code, oauthErr.Description)
+}
+
+// withQuery appends params to a redirect URI that may already have a query.
+func withQuery(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/utils"
+	"errors"
+	"net/http"
+)
+
+// openIDConfigurationHandler godoc
+// @Summary Документ OpenID Connect Discovery
+// @Description Возвращает адреса эндпоинтов и поддерживаемые возможности провайдера OpenID Connect. Адреса строятся от OIDC_ISSUER
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} models.OpenIDConfiguration "Успешный ответ"
+// @Failure 404 {object} models.Error "OpenID Connect недоступен с симметричным ключом подписи"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /.well-known/openid-configuration [get]
+func (h Handler) openIDConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	doc, err := h.service.OpenIDConfiguration()
+	if errors.Is(err, apperrors.ErrOIDCUnavailable) {
+		utils.WriteError(w, http.StatusNotFound, "OpenID Connect недоступен: требуется асимметричный ключ подписи")
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка получения конфигурации OpenID")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.SendJSON(w, http.StatusOK, doc)
+}
+
+// userInfoHandler godoc
+// @Summary Информация о пользователе OpenID Connect
+// @Description Возвращает стандартные claims пользователя в зависимости от scope access токена: sub для openid, email и email_verified для email. Токен без scope openid отклоняется
+// @Tags oidc
+// @Produce json
+// @Success 200 {object} models.UserInfo "Успешный ответ"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.OAuthError "У токена нет scope openid (insufficient_scope)"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /userinfo [get]
+// @Security BearerAuth
+// @Example success {"sub": "b3b3b3b3-b3b3-b3b3-b3b3-b3b3b3b3b3b3", "email": "user@example.com", "email_verified": false}
+// @Example error {"error": "insufficient_scope", "error_description": "the access token requires scope openid"}
+func (h Handler) userInfoHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		utils.WriteError(w, http.StatusUnauthorized, "пользователь не авторизован")
+		return
+	}
+	scope, _ := r.Context().Value("scope").(string)
+
+	info, err := h.service.UserInfo(r.Context(), userID, scope)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrUserNotFound) {
+			utils.WriteError(w, http.StatusUnauthorized, "пользователь не найден")
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка получения информации о пользователе")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.SendJSON(w, http.StatusOK, info)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/utils"
+	"auth-service/models"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// listOutboxHandler godoc
+// @Summary Список событий вебхуков
+// @Description Возвращает последние события outbox с указанным статусом, по умолчанию недоставленные (dead). Требуется разрешение outbox:read
+// @Tags admin
+// @Produce json
+// @Param status query string false "pending, delivered или dead (по умолчанию dead)"
+// @Param limit query int false "Количество событий (по умолчанию и максимум 100)"
+// @Success 200 {object} models.OutboxEventsResponse "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.Error "Недостаточно прав"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /admin/outbox [get]
+// @Security BearerAuth
+// @Example success {"events": [{"id": 42, "type": "new_ip", "payload": {"user_id": "...", "old_ip": "203.0.113.7", "new_ip": "198.51.100.20"}, "status": "dead", "attempts": 10, "last_error": "webhook returned status 503"}]}
+func (h Handler) listOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.OutboxDead
+	}
+	if !slices.Contains([]string{models.OutboxPending, models.OutboxDelivered, models.OutboxDead}, status) {
+		utils.WriteError(w, http.StatusBadRequest, "неверный параметр status")
+		return
+	}
+
+	var err error
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			utils.WriteError(w, http.StatusBadRequest, "неверный параметр limit")
+			return
+		}
+	}
+
+	resp, err := h.service.ListOutboxEvents(r.Context(), status, limit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка получения событий")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
+
+// replayOutboxEventHandler godoc
+// @Summary Повторить доставку события
+// @Description Возвращает недоставленное (dead) событие в очередь с новым запасом попыток. Требуется разрешение outbox:write
+// @Tags admin
+// @Produce json
+// @Param id path int true "ID события"
+// @Success 200 {object} nil "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.Error "Недостаточно прав"
+// @Failure 404 {object} models.Error "Событие не найдено"
+// @Failure 409 {object} models.Error "Событие не в статусе dead"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /admin/outbox/{id}/replay [post]
+// @Security BearerAuth
+// @Example error {"message": "событие не в статусе dead"}
+func (h Handler) replayOutboxEventHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := r.Context().Value("user_id").(string)
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат id")
+		return
+	}
+
+	err = h.service.ReplayOutboxEvent(r.Context(), actorID, id, utils.GetIP(r), r.UserAgent())
+	switch {
+	case err == nil:
+		utils.SendJSON(w, http.StatusOK, nil)
+	case errors.Is(err, apperrors.ErrOutboxEventNotFound):
+		utils.WriteError(w, http.StatusNotFound, "событие не найдено")
+	case errors.Is(err, apperrors.ErrOutboxEventNotDead):
+		utils.WriteError(w, http.StatusConflict, "событие не в статусе dead")
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка повтора доставки")
+	}
+}
+
+// replayDeadOutboxHandler godoc
+// @Summary Повторить доставку всех недоставленных событий
+// @Description Возвращает в очередь все события в статусе dead. Требуется разрешение outbox:write
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.OutboxReplayResponse "Успешный ответ"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.Error "Недостаточно прав"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /admin/outbox/replay [post]
+// @Security BearerAuth
+// @Example success {"replayed": 3}
+func (h Handler) replayDeadOutboxHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := r.Context().Value("user_id").(string)
+
+	resp, err := h.service.ReplayDeadOutboxEvents(r.Context(), actorID, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка повтора доставки")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
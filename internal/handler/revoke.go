@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"auth-service/internal/utils"
+	"net/http"
+)
+
+// revokeHandler godoc
+// @Summary Отзыв токена (RFC 7009)
+// @Description Отзывает пару токенов по access или refresh токену. Для неизвестных и уже отозванных токенов также возвращает 200
+// @Tags auth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access или refresh токен"
+// @Param token_type_hint formData string false "access_token или refresh_token"
+// @Success 200 {object} nil "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 429 {object} models.Error "Превышен лимит запросов"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /revoke [post]
+// @Example error {"message": "token обязателен"}
+func (h Handler) revokeHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат запроса")
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		utils.WriteError(w, http.StatusBadRequest, "token обязателен")
+		return
+	}
+
+	if err := h.service.Revoke(r.Context(), token, r.PostForm.Get("token_type_hint")); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка отзыва токена")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, nil)
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/utils"
+	"auth-service/models"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// listRolesHandler godoc
+// @Summary Список ролей
+// @Description Возвращает все роли с их разрешениями. Требуется разрешение roles:read
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.RolesResponse "Успешный ответ"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.Error "Недостаточно прав"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /admin/roles [get]
+// @Security BearerAuth
+// @Example success {"roles": [{"name": "admin", "description": "Full access to the admin API", "permissions": ["roles:read", "roles:write"], "created_at": "2025-01-01T00:00:00Z", "updated_at": "2025-01-01T00:00:00Z"}]}
+func (h Handler) listRolesHandler(w http.ResponseWriter, r *http.Request) {
+	resp, err := h.service.ListRoles(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка получения ролей")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
+
+// getRoleHandler godoc
+// @Summary Получить роль
+// @Description Возвращает роль и ее разрешения. Требуется разрешение roles:read
+// @Tags admin
+// @Produce json
+// @Param name path string true "Имя роли"
+// @Success 200 {object} models.Role "Успешный ответ"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.Error "Недостаточно прав"
+// @Failure 404 {object} models.Error "Роль не найдена"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /admin/roles/{name} [get]
+// @Security BearerAuth
+// @Example error {"message": "роль не найдена"}
+func (h Handler) getRoleHandler(w http.ResponseWriter, r *http.Request) {
+	role, err := h.service.GetRole(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		writeRoleError(w, err, "ошибка получения роли")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, role)
+}
+
+// createRoleHandler godoc
+// @Summary Создать роль
+// @Description Создает роль с набором разрешений. Имя роли: строчные латинские буквы, цифры, "_" и "-". Требуется разрешение roles:write
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param data body models.RoleRequest true "Роль"
+// @Success 201 {object} models.Role "Роль создана"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.Error "Недостаточно прав"
+// @Failure 409 {object} models.Error "Роль уже существует"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /admin/roles [post]
+// @Security BearerAuth
+// @Example request {"name": "support", "description": "Support staff", "permissions": ["roles:read"]}
+// @Example error {"message": "роль уже существует"}
+func (h Handler) createRoleHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := r.Context().Value("user_id").(string)
+
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат JSON")
+		return
+	}
+
+	role, err := h.service.CreateRole(r.Context(), actorID, req, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		writeRoleError(w, err, "ошибка создания роли")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusCreated, role)
+}
+
+// updateRoleHandler godoc
+// @Summary Изменить роль
+// @Description Заменяет описание и разрешения роли, имя роли не меняется. Требуется разрешение roles:write
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Имя роли"
+// @Param data body models.RoleRequest true "Новое описание и разрешения"
+// @Success 200 {object} models.Role "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.Error "Недостаточно прав"
+// @Failure 404 {object} models.Error "Роль не найдена"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /admin/roles/{name} [put]
+// @Security BearerAuth
+// @Example request {"description": "Support staff", "permissions": ["roles:read"]}
+func (h Handler) updateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := r.Context().Value("user_id").(string)
+
+	var req models.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат JSON")
+		return
+	}
+
+	role, err := h.service.UpdateRole(r.Context(), actorID, chi.URLParam(r, "name"), req, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		writeRoleError(w, err, "ошибка изменения роли")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, role)
+}
+
+// deleteRoleHandler godoc
+// @Summary Удалить роль
+// @Description Удаляет роль и снимает ее со всех пользователей. Требуется разрешение roles:write
+// @Tags admin
+// @Produce json
+// @Param name path string true "Имя роли"
+// @Success 200 {object} nil "Успешный ответ"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.Error "Недостаточно прав"
+// @Failure 404 {object} models.Error "Роль не найдена"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /admin/roles/{name} [delete]
+// @Security BearerAuth
+func (h Handler) deleteRoleHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := r.Context().Value("user_id").(string)
+
+	if err := h.service.DeleteRole(r.Context(), actorID, chi.URLParam(r, "name"), utils.GetIP(r), r.UserAgent()); err != nil {
+		writeRoleError(w, err, "ошибка удаления роли")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, nil)
+}
+
+// assignRoleHandler godoc
+// @Summary Назначить роль пользователю
+// @Description Назначает роль пользователю, повторное назначение ничего не меняет. Роль попадает в claim roles access токенов со следующего входа или обновления. Требуется разрешение roles:write
+// @Tags admin
+// @Produce json
+// @Param name path string true "Имя роли"
+// @Param user_id path string true "ID пользователя"
+// @Success 200 {object} nil "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.Error "Недостаточно прав"
+// @Failure 404 {object} models.Error "Роль или пользователь не найдены"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /admin/roles/{name}/users/{user_id} [put]
+// @Security BearerAuth
+func (h Handler) assignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := r.Context().Value("user_id").(string)
+
+	userID := chi.URLParam(r, "user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат user_id")
+		return
+	}
+
+	err := h.service.AssignRole(r.Context(), actorID, userID, chi.URLParam(r, "name"), utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		writeRoleError(w, err, "ошибка назначения роли")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, nil)
+}
+
+// unassignRoleHandler godoc
+// @Summary Снять роль с пользователя
+// @Description Снимает роль с пользователя. Уже выданные access токены сохраняют роль до истечения. Требуется разрешение roles:write
+// @Tags admin
+// @Produce json
+// @Param name path string true "Имя роли"
+// @Param user_id path string true "ID пользователя"
+// @Success 200 {object} nil "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 403 {object} models.Error "Недостаточно прав"
+// @Failure 404 {object} models.Error "Роль не назначена пользователю"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /admin/roles/{name}/users/{user_id} [delete]
+// @Security BearerAuth
+func (h Handler) unassignRoleHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := r.Context().Value("user_id").(string)
+
+	userID := chi.URLParam(r, "user_id")
+	if _, err := uuid.Parse(userID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат user_id")
+		return
+	}
+
+	err := h.service.UnassignRole(r.Context(), actorID, userID, chi.URLParam(r, "name"), utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		writeRoleError(w, err, "ошибка снятия роли")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, nil)
+}
+
+func writeRoleError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, apperrors.ErrRoleNotFound):
+		utils.WriteError(w, http.StatusNotFound, "роль не найдена")
+	case errors.Is(err, apperrors.ErrUserNotFound):
+		utils.WriteError(w, http.StatusNotFound, "пользователь не найден")
+	case errors.Is(err, apperrors.ErrRoleNotAssigned):
+		utils.WriteError(w, http.StatusNotFound, "роль не назначена пользователю")
+	case errors.Is(err, apperrors.ErrRoleExists):
+		utils.WriteError(w, http.StatusConflict, "роль уже существует")
+	case errors.Is(err, apperrors.ErrInvalidRoleName):
+		utils.WriteError(w, http.StatusBadRequest, "неверное имя роли")
+	case errors.Is(err, apperrors.ErrUnknownPermission):
+		utils.WriteError(w, http.StatusBadRequest, "неизвестное разрешение")
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, fallback)
+	}
+}
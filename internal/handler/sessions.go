@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/utils"
+	"auth-service/models"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// listSessionsHandler godoc
+// @Summary Список активных сессий
+// @Description Возвращает активные сессии пользователя с описанием устройства, IP и временем последнего использования
+// @Tags user
+// @Produce json
+// @Param limit query int false "Количество сессий на странице (по умолчанию 20, максимум 100)"
+// @Param cursor query string false "Курсор следующей страницы"
+// @Success 200 {object} models.SessionsResponse "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /sessions [get]
+// @Security BearerAuth
+// @Example success {"sessions": [{"pair_id": "...", "device": "Chrome 120 on Windows 10", "ip": "203.0.113.7", "current": true}], "next_cursor": "MTI"}
+// @Example error {"message": "неверный курсор"}
+func (h Handler) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		utils.WriteError(w, http.StatusUnauthorized, "пользователь не авторизован")
+		return
+	}
+
+	currentPairID, _ := r.Context().Value("token_pair_id").(string)
+
+	var err error
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			utils.WriteError(w, http.StatusBadRequest, "неверный параметр limit")
+			return
+		}
+	}
+
+	resp, err := h.service.ListSessions(r.Context(), userID, currentPairID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrInvalidCursor) {
+			utils.WriteError(w, http.StatusBadRequest, "неверный курсор")
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка получения сессий")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
+
+// revokeSessionHandler godoc
+// @Summary Завершить сессию
+// @Description Отзывает выбранную сессию пользователя вместе со всей цепочкой ее refresh токенов
+// @Tags user
+// @Produce json
+// @Param pair_id path string true "token_pair_id сессии"
+// @Success 200 {object} nil "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 404 {object} models.Error "Сессия не найдена"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /sessions/{pair_id} [delete]
+// @Security BearerAuth
+// @Example error {"message": "сессия не найдена"}
+func (h Handler) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		utils.WriteError(w, http.StatusUnauthorized, "пользователь не авторизован")
+		return
+	}
+
+	pairID := chi.URLParam(r, "pair_id")
+	if _, err := uuid.Parse(pairID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат pair_id")
+		return
+	}
+
+	if err := h.service.RevokeSession(r.Context(), userID, pairID, utils.GetIP(r), r.UserAgent()); err != nil {
+		if errors.Is(err, apperrors.ErrSessionNotFound) {
+			utils.WriteError(w, http.StatusNotFound, "сессия не найдена")
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка завершения сессии")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, nil)
+}
+
+// logoutAllHandler godoc
+// @Summary Выйти на всех устройствах
+// @Description Отзывает все сессии пользователя, при keep_current=true текущая сессия сохраняется
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param data body models.LogoutAllRequest false "Параметры выхода"
+// @Success 200 {object} models.LogoutAllResponse "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /logout/all [post]
+// @Security BearerAuth
+// @Example request {"keep_current": true}
+// @Example success {"revoked": 3}
+func (h Handler) logoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		utils.WriteError(w, http.StatusUnauthorized, "пользователь не авторизован")
+		return
+	}
+
+	var req models.LogoutAllRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			utils.WriteError(w, http.StatusBadRequest, "неверный формат JSON")
+			return
+		}
+	}
+
+	currentPairID, _ := r.Context().Value("token_pair_id").(string)
+
+	revoked, err := h.service.LogoutAll(r.Context(), userID, currentPairID, req.KeepCurrent, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка деавторизации")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, models.LogoutAllResponse{Revoked: revoked})
+}
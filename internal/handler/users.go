@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/utils"
+	"auth-service/models"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// registerHandler godoc
+// @Summary Регистрация пользователя
+// @Description Создает учетную запись по email и паролю. Пароль должен соответствовать политике PASSWORD_*
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.RegisterRequest true "Email и пароль"
+// @Success 201 {object} models.RegisterResponse "Пользователь создан"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 409 {object} models.Error "Пользователь уже существует"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /register [post]
+// @Example request {"email": "user@example.com", "password": "correct horse 42"}
+// @Example success {"user_id": "b3b3b3b3-b3b3-b3b3-b3b3-b3b3b3b3b3b3"}
+// @Example error {"message": "пароль не соответствует требованиям безопасности"}
+func (h Handler) registerHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат JSON")
+		return
+	}
+
+	if req.Email == "" || req.Password == "" {
+		utils.WriteError(w, http.StatusBadRequest, "email и пароль обязательны")
+		return
+	}
+
+	user, err := h.service.Register(r.Context(), req.Email, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrInvalidEmail):
+			utils.WriteError(w, http.StatusBadRequest, "неверный формат email")
+		case errors.Is(err, apperrors.ErrWeakPassword):
+			utils.WriteError(w, http.StatusBadRequest, "пароль не соответствует требованиям безопасности")
+		case errors.Is(err, apperrors.ErrUserExists):
+			utils.WriteError(w, http.StatusConflict, "пользователь с таким email уже существует")
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, "ошибка регистрации пользователя")
+		}
+		return
+	}
+
+	utils.SendJSON(w, http.StatusCreated, models.RegisterResponse{UserID: user.ID})
+}
+
+// loginHandler godoc
+// @Summary Вход по email и паролю
+// @Description Проверяет пароль и выдает новую пару токенов. Если у пользователя включен второй фактор, вместо пары возвращается mfa_token для POST /login/mfa
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param data body models.LoginRequest true "Email и пароль"
+// @Success 200 {object} models.LoginResponse "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Неверный email или пароль"
+// @Failure 429 {object} models.Error "Превышен лимит запросов"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /login [post]
+// @Example request {"email": "user@example.com", "password": "correct horse 42"}
+// @Example success {"access": "eyJhbGciOiJIUzI1NiIsInR5cCI6...", "refresh": "..."}
+// @Example mfa {"mfa_required": true, "mfa_token": "eyJhbGciOiJIUzUxMiIsInR5cCI6..."}
+// @Example error {"message": "неверный email или пароль"}
+func (h Handler) loginHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат JSON")
+		return
+	}
+
+	if req.Email == "" || req.Password == "" {
+		utils.WriteError(w, http.StatusBadRequest, "email и пароль обязательны")
+		return
+	}
+
+	resp, err := h.service.Login(r.Context(), req.Email, req.Password, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		if errors.Is(err, apperrors.ErrBadCredentials) {
+			utils.WriteError(w, http.StatusUnauthorized, "неверный email или пароль")
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка входа")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/utils"
+	"auth-service/models"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"net/http"
+)
+
+// webAuthnRegisterBeginHandler godoc
+// @Summary Начало регистрации ключа WebAuthn
+// @Description Возвращает параметры для navigator.credentials.create и session_id, который нужно передать в /webauthn/register/finish
+// @Tags webauthn
+// @Produce json
+// @Success 200 {object} models.WebAuthnRegisterBeginResponse "Успешный ответ"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 404 {object} models.Error "Пользователь не найден"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /webauthn/register/begin [post]
+// @Security BearerAuth
+// @Example success {"session_id": "...", "publicKey": {"challenge": "...", "rp": {"id": "localhost", "name": "auth-service"}}}
+// @Example error {"message": "пользователь не найден"}
+func (h Handler) webAuthnRegisterBeginHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		utils.WriteError(w, http.StatusUnauthorized, "пользователь не авторизован")
+		return
+	}
+
+	resp, err := h.service.BeginWebAuthnRegistration(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrUserNotFound) {
+			utils.WriteError(w, http.StatusNotFound, "пользователь не найден")
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка начала регистрации ключа")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
+
+// webAuthnRegisterFinishHandler godoc
+// @Summary Завершение регистрации ключа WebAuthn
+// @Description Проверяет ответ аутентификатора (аттестация none) и сохраняет ключ пользователя
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param data body models.WebAuthnRegisterFinishRequest true "session_id и ответ navigator.credentials.create"
+// @Success 201 {object} models.WebAuthnCredentialResponse "Ключ сохранен"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 409 {object} models.Error "Ключ уже зарегистрирован"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /webauthn/register/finish [post]
+// @Security BearerAuth
+// @Example error {"message": "проверка ключа не пройдена"}
+func (h Handler) webAuthnRegisterFinishHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("user_id").(string)
+	if !ok || userID == "" {
+		utils.WriteError(w, http.StatusUnauthorized, "пользователь не авторизован")
+		return
+	}
+
+	var req models.WebAuthnRegisterFinishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат JSON")
+		return
+	}
+
+	if _, err := uuid.Parse(req.SessionID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат session_id")
+		return
+	}
+
+	resp, err := h.service.FinishWebAuthnRegistration(r.Context(), userID, req, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrChallengeNotFound):
+			utils.WriteError(w, http.StatusBadRequest, "сессия регистрации не найдена или истекла")
+		case errors.Is(err, apperrors.ErrInvalidCredential):
+			utils.WriteError(w, http.StatusBadRequest, "проверка ключа не пройдена")
+		case errors.Is(err, apperrors.ErrCredentialExists):
+			utils.WriteError(w, http.StatusConflict, "ключ уже зарегистрирован")
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, "ошибка регистрации ключа")
+		}
+		return
+	}
+
+	utils.SendJSON(w, http.StatusCreated, resp)
+}
+
+// webAuthnLoginBeginHandler godoc
+// @Summary Начало входа по ключу WebAuthn
+// @Description Возвращает параметры для navigator.credentials.get. Браузер предлагает сохраненные passkey, allowCredentials всегда пуст, чтобы ответ не выдавал наличие пользователя и его ключей; известный email только ограничивает вход ключами этого пользователя
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param data body models.WebAuthnLoginBeginRequest false "Email пользователя"
+// @Success 200 {object} models.WebAuthnLoginBeginResponse "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 429 {object} models.Error "Превышен лимит запросов"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /webauthn/login/begin [post]
+// @Example request {"email": "user@example.com"}
+// @Example success {"session_id": "...", "publicKey": {"challenge": "...", "rpId": "localhost"}}
+func (h Handler) webAuthnLoginBeginHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.WebAuthnLoginBeginRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "неверный формат JSON")
+			return
+		}
+	}
+
+	resp, err := h.service.BeginWebAuthnLogin(r.Context(), req.Email)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "ошибка начала входа по ключу")
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
+
+// webAuthnLoginFinishHandler godoc
+// @Summary Завершение входа по ключу WebAuthn
+// @Description Проверяет подпись аутентификатора и счетчик подписей и выдает пару токенов. Ключ, у которого счетчик не вырос, отключается как клонированный
+// @Tags webauthn
+// @Accept json
+// @Produce json
+// @Param data body models.WebAuthnLoginFinishRequest true "session_id и ответ navigator.credentials.get"
+// @Success 200 {object} models.TokensResponse "Успешный ответ"
+// @Failure 400 {object} models.Error "Некорректный запрос"
+// @Failure 401 {object} models.Error "Ошибка авторизации"
+// @Failure 429 {object} models.Error "Превышен лимит запросов"
+// @Failure 500 {object} models.Error "Внутренняя ошибка сервера"
+// @Router /webauthn/login/finish [post]
+// @Example success {"access": "eyJhbGciOiJIUzI1NiIsInR5cCI6...", "refresh": "..."}
+// @Example error {"message": "ключ отключен: обнаружен клон аутентификатора"}
+func (h Handler) webAuthnLoginFinishHandler(w http.ResponseWriter, r *http.Request) {
+	var req models.WebAuthnLoginFinishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат JSON")
+		return
+	}
+
+	if _, err := uuid.Parse(req.SessionID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "неверный формат session_id")
+		return
+	}
+
+	resp, err := h.service.FinishWebAuthnLogin(r.Context(), req, utils.GetIP(r), r.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrChallengeNotFound):
+			utils.WriteError(w, http.StatusBadRequest, "сессия входа не найдена или истекла")
+		case errors.Is(err, apperrors.ErrCredentialCloned):
+			utils.WriteError(w, http.StatusUnauthorized, "ключ отключен: обнаружен клон аутентификатора")
+		case errors.Is(err, apperrors.ErrCredentialNotFound), errors.Is(err, apperrors.ErrInvalidCredential):
+			utils.WriteError(w, http.StatusUnauthorized, "проверка ключа не пройдена")
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, "ошибка входа по ключу")
+		}
+		return
+	}
+
+	utils.SendJSON(w, http.StatusOK, resp)
+}
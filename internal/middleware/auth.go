@@ -8,7 +8,33 @@ import (
 	"strings"
 )
 
-func AuthMiddleware() func(http.Handler) http.Handler {
+// Principal types stored in the request context under "principal_type".
+const (
+	PrincipalUser   = "user"
+	PrincipalClient = "client"
+)
+
+type RevocationChecker interface {
+	IsTokenPairRevoked(pairID string) bool
+}
+
+// FormBearerToken takes the access token from the access_token field of a
+// form body (RFC 6750 section 2.2) when no Authorization header is sent. A
+// browser can not attach a header to a navigation, but a page can submit a
+// form to POST /authorize and follow the redirect to the client.
+func FormBearerToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		if r.Header.Get("Authorization") == "" && strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+			if token := r.PostFormValue("access_token"); token != "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func AuthMiddleware(revocations RevocationChecker) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -19,14 +45,50 @@ func AuthMiddleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			userID, err := auth.GetUserIDFromToken(tokenString)
+			claims, err := auth.ParseAndValidateToken(tokenString)
 			if err != nil {
 				utils.WriteError(w, http.StatusUnauthorized, "невалидный access токен")
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), "user_id", userID)
+			userID, _ := claims["user_id"].(string)
+			pairID, _ := claims["token_pair_id"].(string)
+			clientID, _ := claims["client_id"].(string)
+			scope, _ := claims["scope"].(string)
+
+			// client_credentials tokens carry the client as sub and have
+			// neither a user nor a token pair to revoke.
+			if userID == "" {
+				if sub, _ := claims["sub"].(string); clientID == "" || sub != clientID {
+					utils.WriteError(w, http.StatusUnauthorized, "невалидный access токен")
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), "principal_type", PrincipalClient)
+				ctx = context.WithValue(ctx, "client_id", clientID)
+				ctx = context.WithValue(ctx, "scope", scope)
+				ctx = context.WithValue(ctx, "access_token", tokenString)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			if pairID == "" {
+				utils.WriteError(w, http.StatusUnauthorized, "невалидный access токен")
+				return
+			}
+
+			if revocations.IsTokenPairRevoked(pairID) {
+				utils.WriteError(w, http.StatusUnauthorized, "пользователь деавторизован")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), "principal_type", PrincipalUser)
+			ctx = context.WithValue(ctx, "user_id", userID)
+			ctx = context.WithValue(ctx, "roles", auth.ClaimStrings(claims, "roles"))
+			ctx = context.WithValue(ctx, "client_id", clientID)
+			ctx = context.WithValue(ctx, "scope", scope)
 			ctx = context.WithValue(ctx, "access_token", tokenString)
+			ctx = context.WithValue(ctx, "token_pair_id", pairID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
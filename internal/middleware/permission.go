@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"auth-service/internal/utils"
+	"context"
+	"github.com/gookit/slog"
+	"net/http"
+)
+
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, roles []string, permission string) (bool, error)
+}
+
+// RequirePermission lets through requests whose access token carries a role
+// granting the permission. It must be used after AuthMiddleware. Roles come
+// from the token, so a change takes effect with the next refresh.
+func RequirePermission(checker PermissionChecker, permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			roles, _ := r.Context().Value("roles").([]string)
+
+			ok, err := checker.HasPermission(r.Context(), roles, permission)
+			if err != nil {
+				slog.Error("failed to check permission", "permission", permission, "err", err)
+				utils.WriteError(w, http.StatusInternalServerError, "ошибка проверки прав доступа")
+				return
+			}
+			if !ok {
+				utils.WriteError(w, http.StatusForbidden, "недостаточно прав")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+type rolePermissions map[string][]string
+
+func (p rolePermissions) HasPermission(_ context.Context, roles []string, permission string) (bool, error) {
+	if slices.Contains(roles, "broken") {
+		return false, errors.New("database is down")
+	}
+	for _, role := range roles {
+		if slices.Contains(p[role], permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestRequirePermission(t *testing.T) {
+	checker := rolePermissions{
+		"admin":   {"roles:read", "roles:write"},
+		"support": {"roles:read"},
+	}
+	handler := RequirePermission(checker, "roles:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name  string
+		roles []string
+		want  int
+	}{
+		{"role with the permission", []string{"support", "admin"}, http.StatusNoContent},
+		{"role without the permission", []string{"support"}, http.StatusForbidden},
+		{"no roles", nil, http.StatusForbidden},
+		{"checker failure", []string{"broken"}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, "/admin/roles/support", nil)
+			if tt.roles != nil {
+				r = r.WithContext(context.WithValue(r.Context(), "roles", tt.roles))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"auth-service/internal/ratelimit"
+	"auth-service/internal/utils"
+	"github.com/gookit/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimit takes a token from the bucket of the client IP and, when userID
+// is set and returns one, from the bucket of the user. Throttled requests get
+// 429 with Retry-After; every response carries the RateLimit-* headers of the
+// bucket closest to exhaustion. The limits fail open when the store is
+// unavailable.
+func RateLimit(limiter *ratelimit.Limiter, userID func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := limiter.AllowIP(r.Context(), utils.GetIP(r))
+			if err != nil {
+				slog.Error("rate limit by ip failed", "err", err)
+				result = ratelimit.Result{Allowed: true}
+			}
+
+			var id string
+			if userID != nil {
+				id = userID(r)
+			}
+			if result.Allowed && id != "" {
+				userResult, err := limiter.AllowUser(r.Context(), id)
+				if err != nil {
+					slog.Error("rate limit by user failed", "user_id", id, "err", err)
+				} else if tighter(userResult, result) {
+					result = userResult
+				}
+			}
+
+			if result.Limit > 0 {
+				w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+				w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+				w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			}
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+				utils.WriteError(w, http.StatusTooManyRequests, "слишком много запросов, повторите позже")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserIDFromContext returns the user authenticated by AuthMiddleware.
+func UserIDFromContext(r *http.Request) string {
+	userID, _ := r.Context().Value("user_id").(string)
+	return userID
+}
+
+// tighter reports whether a is closer to exhaustion than b. Disabled limits
+// have a zero Limit and are never tighter.
+func tighter(a, b ratelimit.Result) bool {
+	switch {
+	case a.Limit == 0:
+		return false
+	case b.Limit == 0 || !a.Allowed:
+		return true
+	default:
+		return a.Remaining < b.Remaining
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
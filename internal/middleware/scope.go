@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/utils"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// RequireScopes rejects requests whose access token was not granted all of
+// the scopes with 403 insufficient_scope (RFC 6750 section 3.1). It must be
+// used after AuthMiddleware.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	required := strings.Join(scopes, " ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, _ := r.Context().Value("scope").(string)
+			fields := strings.Fields(granted)
+
+			for _, scope := range scopes {
+				if !slices.Contains(fields, scope) {
+					w.Header().Set("WWW-Authenticate",
+						fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, required))
+					utils.WriteOAuthError(w, http.StatusForbidden, apperrors.OAuthInsufficientScope,
+						"the access token requires scope "+required)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"auth-service/models"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in the process, so every replica counts its own
+// requests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]models.RateLimitBucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]models.RateLimitBucket),
+	}
+}
+
+func (m *MemoryStore) UpdateRateLimitBucket(_ context.Context, key string, update func(models.RateLimitBucket) models.RateLimitBucket) (models.RateLimitBucket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = models.RateLimitBucket{Key: key}
+	}
+	bucket = update(bucket)
+	m.buckets[key] = bucket
+
+	return bucket, nil
+}
+
+func (m *MemoryStore) FindRateLimitBucket(_ context.Context, key string) (models.RateLimitBucket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bucket, ok := m.buckets[key]
+	if !ok {
+		return models.RateLimitBucket{Key: key}, nil
+	}
+	return bucket, nil
+}
+
+func (m *MemoryStore) DeleteStaleRateLimitBuckets(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for key, bucket := range m.buckets {
+		if bucket.UpdatedAt.Before(before) && bucket.LockedUntil.Before(before) {
+			delete(m.buckets, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
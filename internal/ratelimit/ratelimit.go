@@ -0,0 +1,244 @@
+package ratelimit
+
+import (
+	"auth-service/config"
+	"auth-service/models"
+	"context"
+	"github.com/gookit/slog"
+	"math"
+	"time"
+)
+
+// Store keeps token buckets. MemoryStore serves a single instance, the
+// Postgres repository shares the limits between replicas.
+type Store interface {
+	UpdateRateLimitBucket(ctx context.Context, key string, update func(models.RateLimitBucket) models.RateLimitBucket) (models.RateLimitBucket, error)
+	FindRateLimitBucket(ctx context.Context, key string) (models.RateLimitBucket, error)
+	DeleteStaleRateLimitBuckets(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Limit allows Burst requests at once, refilled evenly over Period. A zero
+// Burst disables the limit.
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+func (l Limit) Enabled() bool {
+	return l.Burst > 0 && l.Period > 0
+}
+
+func (l Limit) perSecond() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the time until the next request is allowed, zero when
+	// this one was.
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+}
+
+// Lockout locks a key out for Duration once MaxFailures failures were
+// recorded within Window. A zero MaxFailures disables it.
+type Lockout struct {
+	MaxFailures int
+	Window      time.Duration
+	Duration    time.Duration
+}
+
+func (l Lockout) Enabled() bool {
+	return l.MaxFailures > 0 && l.Window > 0 && l.Duration > 0
+}
+
+type Limiter struct {
+	store    Store
+	ip       Limit
+	user     Limit
+	refresh  Lockout
+	mfa      Lockout
+	mfaToken Lockout
+}
+
+func NewLimiter(store Store, cfg config.RateLimit, mfa config.MFA) *Limiter {
+	return &Limiter{
+		store: store,
+		ip:    Limit{Burst: cfg.IPBurst, Period: cfg.IPPeriod},
+		user:  Limit{Burst: cfg.UserBurst, Period: cfg.UserPeriod},
+		refresh: Lockout{
+			MaxFailures: cfg.LockoutMaxFailures,
+			Window:      cfg.LockoutWindow,
+			Duration:    cfg.LockoutDuration,
+		},
+		mfa: Lockout{
+			MaxFailures: mfa.LockoutMaxFailures,
+			Window:      mfa.LockoutWindow,
+			Duration:    mfa.LockoutDuration,
+		},
+		// An mfa_pending token is burnt for the rest of its lifetime.
+		mfaToken: Lockout{
+			MaxFailures: mfa.MaxAttempts,
+			Window:      mfa.PendingTTL,
+			Duration:    mfa.PendingTTL,
+		},
+	}
+}
+
+func (l *Limiter) AllowIP(ctx context.Context, ip string) (Result, error) {
+	return l.Allow(ctx, "ip:"+ip, l.ip)
+}
+
+func (l *Limiter) AllowUser(ctx context.Context, userID string) (Result, error) {
+	return l.Allow(ctx, "user:"+userID, l.user)
+}
+
+// Allow takes a token from the bucket of key.
+func (l *Limiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if !limit.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+
+	var result Result
+	_, err := l.store.UpdateRateLimitBucket(ctx, key, func(bucket models.RateLimitBucket) models.RateLimitBucket {
+		bucket, result = take(bucket, limit, time.Now())
+		return bucket
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return result, nil
+}
+
+// RefreshLockedUntil returns when the refresh lockout of the user ends, zero
+// when the user is not locked out.
+func (l *Limiter) RefreshLockedUntil(ctx context.Context, userID string) (time.Time, error) {
+	return l.LockedUntil(ctx, "refresh_failures:"+userID, l.refresh)
+}
+
+// RecordRefreshFailure counts a refresh token mismatch of the user and
+// returns the end of the lockout once the user is locked out.
+func (l *Limiter) RecordRefreshFailure(ctx context.Context, userID string) (time.Time, error) {
+	return l.RecordFailure(ctx, "refresh_failures:"+userID, l.refresh)
+}
+
+// MFALockedUntil returns when the user may try second factor codes again,
+// zero when the user is not locked out.
+func (l *Limiter) MFALockedUntil(ctx context.Context, userID string) (time.Time, error) {
+	return l.LockedUntil(ctx, "mfa_failures:"+userID, l.mfa)
+}
+
+// RecordMFAFailure counts a wrong second factor code of the user, whichever
+// mfa_pending token it came with.
+func (l *Limiter) RecordMFAFailure(ctx context.Context, userID string) (time.Time, error) {
+	return l.RecordFailure(ctx, "mfa_failures:"+userID, l.mfa)
+}
+
+// MFATokenLockedUntil returns when the mfa_pending token with the given jti
+// is accepted again, which is after it expires.
+func (l *Limiter) MFATokenLockedUntil(ctx context.Context, tokenID string) (time.Time, error) {
+	return l.LockedUntil(ctx, "mfa_token:"+tokenID, l.mfaToken)
+}
+
+// RecordMFATokenFailure counts a wrong code presented with the mfa_pending
+// token with the given jti.
+func (l *Limiter) RecordMFATokenFailure(ctx context.Context, tokenID string) (time.Time, error) {
+	return l.RecordFailure(ctx, "mfa_token:"+tokenID, l.mfaToken)
+}
+
+// LockedUntil returns when the lockout of key ends, zero when the key is not
+// locked out.
+func (l *Limiter) LockedUntil(ctx context.Context, key string, lockout Lockout) (time.Time, error) {
+	if !lockout.Enabled() {
+		return time.Time{}, nil
+	}
+
+	bucket, err := l.store.FindRateLimitBucket(ctx, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !time.Now().Before(bucket.LockedUntil) {
+		return time.Time{}, nil
+	}
+	return bucket.LockedUntil, nil
+}
+
+// RecordFailure counts a failure of key. The failure that uses up the
+// allowance of the window locks the key out, and the end of the lockout is
+// returned.
+func (l *Limiter) RecordFailure(ctx context.Context, key string, lockout Lockout) (time.Time, error) {
+	if !lockout.Enabled() {
+		return time.Time{}, nil
+	}
+
+	limit := Limit{Burst: lockout.MaxFailures, Period: lockout.Window}
+	bucket, err := l.store.UpdateRateLimitBucket(ctx, key, func(bucket models.RateLimitBucket) models.RateLimitBucket {
+		now := time.Now()
+		bucket, result := take(bucket, limit, now)
+		if result.Remaining == 0 && !now.Before(bucket.LockedUntil) {
+			bucket.LockedUntil = now.Add(lockout.Duration)
+			// The lockout starts over with a full allowance.
+			bucket.Tokens = float64(limit.Burst)
+		}
+		return bucket
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !time.Now().Before(bucket.LockedUntil) {
+		return time.Time{}, nil
+	}
+	return bucket.LockedUntil, nil
+}
+
+// RunCleanup periodically drops buckets that refilled completely and
+// expired lockouts until ctx is cancelled.
+func (l *Limiter) RunCleanup(ctx context.Context, interval time.Duration) {
+	idle := max(l.ip.Period, l.user.Period, l.refresh.Window, l.mfa.Window, l.mfaToken.Window)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := l.store.DeleteStaleRateLimitBuckets(ctx, now.Add(-idle)); err != nil {
+				slog.Error("failed to delete stale rate limit buckets", "err", err)
+			}
+		}
+	}
+}
+
+func take(bucket models.RateLimitBucket, limit Limit, now time.Time) (models.RateLimitBucket, Result) {
+	burst, rate := float64(limit.Burst), limit.perSecond()
+
+	tokens := burst
+	if !bucket.UpdatedAt.IsZero() {
+		elapsed := max(now.Sub(bucket.UpdatedAt).Seconds(), 0)
+		tokens = min(burst, bucket.Tokens+elapsed*rate)
+	}
+
+	result := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((burst - tokens) / rate)
+
+	bucket.Tokens = tokens
+	bucket.UpdatedAt = now
+	return bucket, result
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
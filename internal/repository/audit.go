@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"auth-service/models"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func (r Repository) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	if err := saveAuditEvent(ctx, r.conn, event); err != nil {
+		return fmt.Errorf("r.conn.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func saveAuditEvent(ctx context.Context, conn execer, event models.AuditEvent) error {
+	details := event.Details
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+
+	var userID interface{}
+	if event.UserID != "" {
+		userID = event.UserID
+	}
+
+	_, err := conn.Exec(ctx, querySaveAuditEvent, userID, event.Type, event.IP, event.UserAgent, details)
+	return err
+}
@@ -4,46 +4,182 @@ import (
 	"auth-service/internal/apperrors"
 	"auth-service/models"
 	"context"
+	"errors"
 	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
 )
 
 func (r Repository) FindRefreshTokenByPairID(ctx context.Context, userID, pairID string) (models.RefreshToken, error) {
-	var token models.RefreshToken
+	token, err := scanRefreshToken(r.conn.QueryRow(ctx, queryFindRefreshTokenByPairID, userID, pairID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.RefreshToken{}, apperrors.ErrTokenIsNotFound
+	}
+	if err != nil {
+		return models.RefreshToken{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
 
-	row := r.conn.QueryRow(ctx, queryFindRefreshTokenByPairID, userID, pairID)
-	err := row.Scan(
-		&token.ID, &token.UserID, &token.TokenHash, &token.TokenPairID,
-		&token.UserAgent, &token.IP, &token.Revoked, &token.CreatedAt)
+	return token, nil
+}
+
+func (r Repository) FindRefreshTokenBySelector(ctx context.Context, selector string) (models.RefreshToken, error) {
+	token, err := scanRefreshToken(r.conn.QueryRow(ctx, queryFindRefreshTokenBySelector, selector))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.RefreshToken{}, apperrors.ErrTokenIsNotFound
+	}
 	if err != nil {
-		return models.RefreshToken{}, fmt.Errorf("r.conn.Exec: %w", err)
+		return models.RefreshToken{}, fmt.Errorf("r.conn.QueryRow: %w", err)
 	}
 
 	return token, nil
 }
 
-func (r Repository) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
-	_, err := r.conn.Exec(ctx, querySaveRefreshToken,
-		token.UserID, token.TokenHash, token.TokenPairID, token.UserAgent, token.IP)
+func (r Repository) FindRefreshTokenByLookup(ctx context.Context, lookup string) (models.RefreshToken, error) {
+	token, err := scanRefreshToken(r.conn.QueryRow(ctx, queryFindRefreshTokenByLookup, lookup))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.RefreshToken{}, apperrors.ErrTokenIsNotFound
+	}
 	if err != nil {
+		return models.RefreshToken{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return token, nil
+}
+
+func (r Repository) SaveRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	if err := saveRefreshToken(ctx, r.conn, token); err != nil {
 		return fmt.Errorf("r.conn.Exec: %w", err)
 	}
 
 	return nil
 }
 
+func saveRefreshToken(ctx context.Context, conn execer, token models.RefreshToken) error {
+	_, err := conn.Exec(ctx, querySaveRefreshToken,
+		token.UserID, token.Selector, token.VerifierHash, token.TokenPairID,
+		token.FamilyID, token.ParentID, token.UserAgent, token.IP, token.AMR, token.ClientID, token.Scope, token.AuthTime, token.ExpiresAt)
+	return err
+}
+
 func (r Repository) RevokeRefreshTokenByPairID(ctx context.Context, userID, pairID string) error {
-	tag, err := r.conn.Exec(ctx, `
-  UPDATE refresh_tokens 
-  SET revoked = true 
-  WHERE user_id = $1 AND token_pair_id = $2 AND revoked = false
-`, userID, pairID)
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryRevokeRefreshTokenByPairID, userID, pairID)
+		if err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		if tag.RowsAffected() == 0 {
+			return apperrors.ErrAlreadyLoggedOut
+		}
+
+		return notifyRevoked(ctx, tx, []string{pairID})
+	})
 	if err != nil {
-		return fmt.Errorf("r.conn.Exec: %w", err)
+		return err
 	}
 
-	if tag.RowsAffected() == 0 {
-		return apperrors.ErrAlreadyLoggedOut
+	return nil
+}
+
+// RotateRefreshToken revokes the pair a refresh token was presented with and
+// saves its successor. Webhook events caused by the rotation are queued in
+// the same transaction.
+func (r Repository) RotateRefreshToken(ctx context.Context, userID, pairID string, token models.RefreshToken, events []models.OutboxEvent) error {
+	return pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryRevokeRefreshTokenByPairID, userID, pairID)
+		if err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		// A concurrent refresh has already rotated the pair.
+		if tag.RowsAffected() == 0 {
+			return apperrors.ErrAlreadyLoggedOut
+		}
+
+		if err = saveRefreshToken(ctx, tx, token); err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		if err = saveOutboxEvents(ctx, tx, events); err != nil {
+			return err
+		}
+
+		return notifyRevoked(ctx, tx, []string{pairID})
+	})
+}
+
+func (r Repository) RevokeRefreshTokenFamily(ctx context.Context, userID, familyID string, events []models.OutboxEvent) ([]string, error) {
+	var pairIDs []string
+
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		var err error
+		pairIDs, err = collectPairIDs(tx.Query(ctx, queryRevokeRefreshTokenFamily, userID, familyID))
+		if err != nil {
+			return fmt.Errorf("tx.Query: %w", err)
+		}
+
+		if err = saveOutboxEvents(ctx, tx, events); err != nil {
+			return err
+		}
+
+		return notifyRevoked(ctx, tx, pairIDs)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return pairIDs, nil
+}
+
+// ListRevokedPairs returns the revoked pairs whose access tokens may still be
+// unexpired. Pairs of clients without their own lifetime use defaultTTL.
+func (r Repository) ListRevokedPairs(ctx context.Context, defaultTTL time.Duration) ([]models.RevokedPair, error) {
+	rows, err := r.conn.Query(ctx, queryListRevokedPairs, int64(defaultTTL.Seconds()))
+	if err != nil {
+		return nil, fmt.Errorf("r.conn.Query: %w", err)
+	}
+
+	pairs, err := pgx.CollectRows(rows, pgx.RowToStructByPos[models.RevokedPair])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows: %w", err)
+	}
+
+	return pairs, nil
+}
+
+func (r Repository) ListActiveRefreshTokens(ctx context.Context, userID string, beforeID, limit int) ([]models.RefreshToken, error) {
+	rows, err := r.conn.Query(ctx, queryListActiveRefreshTokens, userID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("r.conn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []models.RefreshToken
+	for rows.Next() {
+		token, err := scanRefreshToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func scanRefreshToken(row pgx.Row) (models.RefreshToken, error) {
+	var token models.RefreshToken
+
+	err := row.Scan(
+		&token.ID, &token.UserID, &token.Selector, &token.VerifierHash,
+		&token.TokenHash, &token.TokenLookup, &token.TokenPairID,
+		&token.FamilyID, &token.ParentID, &token.UserAgent, &token.IP, &token.AMR, &token.ClientID, &token.Scope, &token.Revoked, &token.AuthTime, &token.ExpiresAt, &token.CreatedAt,
+		&token.Rotated)
+	if err != nil {
+		return models.RefreshToken{}, err
+	}
+
+	return token, nil
 }
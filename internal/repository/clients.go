@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+)
+
+func (r Repository) CreateOAuthClient(ctx context.Context, client models.OAuthClient) (models.OAuthClient, error) {
+	err := r.conn.QueryRow(ctx, queryCreateOAuthClient,
+		client.ID, client.Name, client.SecretHash, nonNil(client.RedirectURIs), nonNil(client.GrantTypes), nonNil(client.Scopes),
+		nonNil(client.Audiences), int64(client.AccessTokenTTL.Seconds()), int64(client.RefreshTokenTTL.Seconds())).Scan(&client.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return models.OAuthClient{}, apperrors.ErrClientExists
+	}
+	if err != nil {
+		return models.OAuthClient{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return client, nil
+}
+
+func (r Repository) FindOAuthClient(ctx context.Context, clientID string) (models.OAuthClient, error) {
+	client, err := scanOAuthClient(r.conn.QueryRow(ctx, queryFindOAuthClient, clientID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.OAuthClient{}, apperrors.ErrClientNotFound
+	}
+	if err != nil {
+		return models.OAuthClient{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return client, nil
+}
+
+func (r Repository) ListOAuthClients(ctx context.Context) ([]models.OAuthClient, error) {
+	rows, err := r.conn.Query(ctx, queryListOAuthClients)
+	if err != nil {
+		return nil, fmt.Errorf("r.conn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []models.OAuthClient
+	for rows.Next() {
+		client, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		clients = append(clients, client)
+	}
+
+	return clients, rows.Err()
+}
+
+// MaxOAuthClientAccessTTL returns the longest access token lifetime
+// registered for a client, zero when every client uses the default.
+func (r Repository) MaxOAuthClientAccessTTL(ctx context.Context) (time.Duration, error) {
+	var seconds int64
+	if err := r.conn.QueryRow(ctx, queryMaxOAuthClientAccessTTL).Scan(&seconds); err != nil {
+		return 0, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func scanOAuthClient(row pgx.Row) (models.OAuthClient, error) {
+	var client models.OAuthClient
+	var accessTTL, refreshTTL int64
+
+	err := row.Scan(&client.ID, &client.Name, &client.SecretHash, &client.RedirectURIs, &client.GrantTypes, &client.Scopes,
+		&client.Audiences, &accessTTL, &refreshTTL, &client.CreatedAt)
+	if err != nil {
+		return models.OAuthClient{}, err
+	}
+	client.AccessTokenTTL = time.Duration(accessTTL) * time.Second
+	client.RefreshTokenTTL = time.Duration(refreshTTL) * time.Second
+
+	return client, nil
+}
+
+// nonNil keeps NOT NULL array columns from receiving a NULL for a nil slice.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
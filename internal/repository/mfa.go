@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func (r Repository) FindTOTP(ctx context.Context, userID string) (models.TOTP, error) {
+	var totp models.TOTP
+	err := r.conn.QueryRow(ctx, queryFindTOTP, userID).Scan(&totp.UserID, &totp.Secret, &totp.ConfirmedAt, &totp.LastUsedStep)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.TOTP{}, apperrors.ErrMFANotEnrolled
+	}
+	if err != nil {
+		return models.TOTP{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return totp, nil
+}
+
+// SaveTOTPSecret stores a new unconfirmed secret, replacing a previous
+// unconfirmed one. A confirmed secret is never overwritten.
+func (r Repository) SaveTOTPSecret(ctx context.Context, userID, secret string) error {
+	tag, err := r.conn.Exec(ctx, querySaveTOTPSecret, userID, secret)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return apperrors.ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("r.conn.Exec: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return apperrors.ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// ConfirmTOTP enables the second factor and replaces the recovery codes of the
+// user in one transaction.
+func (r Repository) ConfirmTOTP(ctx context.Context, userID string, step int64, codeHashes []string, event models.AuditEvent) error {
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryConfirmTOTP, userID, step)
+		if err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return apperrors.ErrMFAAlreadyEnabled
+		}
+
+		if _, err = tx.Exec(ctx, queryDeleteRecoveryCodes, userID); err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		batch := &pgx.Batch{}
+		for _, hash := range codeHashes {
+			batch.Queue(querySaveRecoveryCode, userID, hash)
+		}
+		if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("tx.SendBatch: %w", err)
+		}
+
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("confirm totp: %w", err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep records step as used. It fails when the same or a later step
+// has already been accepted, which stops a code from being replayed.
+func (r Repository) UseTOTPStep(ctx context.Context, userID string, step int64) error {
+	tag, err := r.conn.Exec(ctx, queryUseTOTPStep, userID, step)
+	if err != nil {
+		return fmt.Errorf("r.conn.Exec: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return apperrors.ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func (r Repository) UseRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	tag, err := r.conn.Exec(ctx, queryUseRecoveryCode, userID, codeHash)
+	if err != nil {
+		return fmt.Errorf("r.conn.Exec: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return apperrors.ErrInvalidMFACode
+	}
+
+	return nil
+}
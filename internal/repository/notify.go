@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"auth-service/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gookit/slog"
+	"time"
+)
+
+const (
+	revocationChannel = "token_revocations"
+	// NOTIFY payloads are limited to 8000 bytes, a quoted uuid takes 39 of them.
+	revocationBatchSize = 150
+)
+
+// notifyRevoked publishes revoked pairs to every instance. Notifications are
+// only delivered when the surrounding transaction commits.
+func notifyRevoked(ctx context.Context, tx execer, pairIDs []string) error {
+	revokedAt := time.Now()
+
+	for start := 0; start < len(pairIDs); start += revocationBatchSize {
+		end := min(start+revocationBatchSize, len(pairIDs))
+
+		payload, err := json.Marshal(models.RevocationEvent{PairIDs: pairIDs[start:end], RevokedAt: revokedAt})
+		if err != nil {
+			return fmt.Errorf("encode revocation event: %w", err)
+		}
+
+		if _, err = tx.Exec(ctx, "SELECT pg_notify($1, $2)", revocationChannel, string(payload)); err != nil {
+			return fmt.Errorf("pg_notify: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ListenRevocations subscribes a dedicated connection to revocation events.
+// ready is called once the subscription is active, so that a full reload
+// made there can not miss events. It returns when the connection fails or
+// ctx is cancelled.
+func (r Repository) ListenRevocations(ctx context.Context, ready func(ctx context.Context) error, handle func(models.RevocationEvent)) error {
+	pooled, err := r.conn.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("r.conn.Acquire: %w", err)
+	}
+	// The connection is left in LISTEN state, so it must not go back to the pool.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err = conn.Exec(ctx, "LISTEN "+revocationChannel); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	if err = ready(ctx); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+
+		var event models.RevocationEvent
+		if err = json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			slog.Error("failed to decode revocation event", "payload", notification.Payload, "err", err)
+			continue
+		}
+
+		handle(event)
+	}
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// SaveAuthorizationCode stores a new code. Used codes are kept for a day
+// after they expire so that a replayed code can still be recognized.
+func (r Repository) SaveAuthorizationCode(ctx context.Context, code models.AuthorizationCode) error {
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, queryDeleteExpiredAuthorizationCodes); err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		_, err := tx.Exec(ctx, querySaveAuthorizationCode,
+			code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, code.CodeChallenge,
+			code.Scope, code.Nonce, code.AMR, code.AuthTime, code.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save authorization code: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeAuthorizationCode marks the code as used and binds it to the token
+// family issued for it. A code presented again is returned together with
+// ErrCodeReused, so the tokens of its family can be revoked.
+func (r Repository) ConsumeAuthorizationCode(ctx context.Context, codeHash, familyID string) (models.AuthorizationCode, error) {
+	var code models.AuthorizationCode
+
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, queryLockAuthorizationCode, codeHash).Scan(
+			&code.CodeHash, &code.ClientID, &code.UserID, &code.RedirectURI, &code.CodeChallenge, &code.Scope,
+			&code.Nonce, &code.AMR, &code.AuthTime, &code.ExpiresAt, &code.UsedAt, &code.FamilyID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apperrors.ErrCodeNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("tx.QueryRow: %w", err)
+		}
+
+		if code.UsedAt != nil {
+			return apperrors.ErrCodeReused
+		}
+		if !time.Now().Before(code.ExpiresAt) {
+			return apperrors.ErrCodeNotFound
+		}
+
+		if _, err = tx.Exec(ctx, queryUseAuthorizationCode, codeHash, familyID); err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+		code.FamilyID = familyID
+
+		return nil
+	})
+	if errors.Is(err, apperrors.ErrCodeReused) {
+		return code, err
+	}
+	if err != nil {
+		return models.AuthorizationCode{}, err
+	}
+
+	return code, nil
+}
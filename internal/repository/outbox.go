@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+func (r Repository) SaveOutboxEvents(ctx context.Context, events []models.OutboxEvent) error {
+	return saveOutboxEvents(ctx, r.conn, events)
+}
+
+func saveOutboxEvents(ctx context.Context, conn execer, events []models.OutboxEvent) error {
+	for _, event := range events {
+		if _, err := conn.Exec(ctx, querySaveOutboxEvent, event.Type, event.Payload); err != nil {
+			return fmt.Errorf("save outbox event: %w", err)
+		}
+	}
+	return nil
+}
+
+// ClaimOutboxEvents returns up to limit due events and counts the delivery
+// attempt. They are not offered again before lease has passed.
+func (r Repository) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	rows, err := r.conn.Query(ctx, queryClaimOutboxEvents, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("r.conn.Query: %w", err)
+	}
+
+	return collectOutboxEvents(rows)
+}
+
+func (r Repository) MarkOutboxEventDelivered(ctx context.Context, id int64) error {
+	if _, err := r.conn.Exec(ctx, queryMarkOutboxEventDelivered, id); err != nil {
+		return fmt.Errorf("r.conn.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r Repository) RetryOutboxEvent(ctx context.Context, id int64, at time.Time, lastError string) error {
+	if _, err := r.conn.Exec(ctx, queryRetryOutboxEvent, id, at, lastError); err != nil {
+		return fmt.Errorf("r.conn.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r Repository) DeadLetterOutboxEvent(ctx context.Context, id int64, lastError string) error {
+	if _, err := r.conn.Exec(ctx, queryDeadLetterOutboxEvent, id, lastError); err != nil {
+		return fmt.Errorf("r.conn.Exec: %w", err)
+	}
+	return nil
+}
+
+func (r Repository) ListOutboxEvents(ctx context.Context, status string, limit int) ([]models.OutboxEvent, error) {
+	rows, err := r.conn.Query(ctx, queryListOutboxEvents, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("r.conn.Query: %w", err)
+	}
+
+	return collectOutboxEvents(rows)
+}
+
+// ReplayOutboxEvent schedules a dead event for immediate delivery with a
+// fresh allowance of attempts.
+func (r Repository) ReplayOutboxEvent(ctx context.Context, id int64, event models.AuditEvent) error {
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		var status string
+		err := tx.QueryRow(ctx, queryFindOutboxEventStatus, id).Scan(&status)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apperrors.ErrOutboxEventNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("tx.QueryRow: %w", err)
+		}
+		if status != models.OutboxDead {
+			return apperrors.ErrOutboxEventNotDead
+		}
+
+		if _, err = tx.Exec(ctx, queryReplayOutboxEvent, id); err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("replay outbox event: %w", err)
+	}
+
+	return nil
+}
+
+func (r Repository) ReplayDeadOutboxEvents(ctx context.Context, event models.AuditEvent) (int64, error) {
+	var replayed int64
+
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryReplayDeadOutboxEvents)
+		if err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+		replayed = tag.RowsAffected()
+
+		if event.Details == nil {
+			event.Details = map[string]interface{}{}
+		}
+		event.Details["replayed"] = replayed
+
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("replay dead outbox events: %w", err)
+	}
+
+	return replayed, nil
+}
+
+func collectOutboxEvents(rows pgx.Rows) ([]models.OutboxEvent, error) {
+	defer rows.Close()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var event models.OutboxEvent
+		err := rows.Scan(&event.ID, &event.Type, &event.Payload, &event.Status, &event.Attempts,
+			&event.NextAttemptAt, &event.LastError, &event.CreatedAt, &event.DeliveredAt)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err: %w", err)
+	}
+
+	return events, nil
+}
@@ -1,13 +1,377 @@
 package repository
 
 const (
+	refreshTokenColumns = `
+		rt.id, rt.user_id, COALESCE(rt.selector, ''), COALESCE(rt.verifier_hash, ''),
+		COALESCE(rt.token_hash, ''), COALESCE(rt.token_lookup, ''), rt.token_pair_id,
+		rt.family_id, rt.parent_id, rt.user_agent, rt.ip, rt.amr, COALESCE(rt.client_id, ''), rt.scope, rt.revoked, rt.auth_time, rt.expires_at, rt.created_at,
+		EXISTS (SELECT 1 FROM refresh_tokens child WHERE child.parent_id = rt.id)`
+
 	queryFindRefreshTokenByPairID = `
-		SELECT id, user_id, token_hash, token_pair_id, user_agent, ip, revoked, created_at
-		FROM refresh_tokens
-		WHERE user_id = $1
-		AND token_pair_id = $2`
+		SELECT` + refreshTokenColumns + `
+		FROM refresh_tokens rt
+		WHERE rt.user_id = $1
+		AND rt.token_pair_id = $2`
+
+	queryFindRefreshTokenBySelector = `
+		SELECT` + refreshTokenColumns + `
+		FROM refresh_tokens rt
+		WHERE rt.selector = $1`
+
+	queryFindRefreshTokenByLookup = `
+		SELECT` + refreshTokenColumns + `
+		FROM refresh_tokens rt
+		WHERE rt.token_lookup = $1`
+
+	queryListActiveRefreshTokens = `
+		SELECT` + refreshTokenColumns + `
+		FROM refresh_tokens rt
+		WHERE rt.user_id = $1
+		AND rt.revoked = false
+		AND rt.expires_at > NOW()
+		AND rt.id < $2
+		ORDER BY rt.id DESC
+		LIMIT $3`
 
 	querySaveRefreshToken = `
-		INSERT INTO refresh_tokens (user_id, token_hash, token_pair_id, user_agent, ip) 
+		INSERT INTO refresh_tokens (user_id, selector, verifier_hash, token_pair_id, family_id, parent_id, user_agent, ip, amr, client_id, scope, auth_time, expires_at) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9::text[], '{}'), NULLIF($10, ''), $11, $12, $13)`
+
+	queryRevokeRefreshTokenByPairID = `
+		UPDATE refresh_tokens
+		SET revoked = true, revoked_at = NOW()
+		WHERE user_id = $1 AND token_pair_id = $2 AND revoked = false`
+
+	queryRevokeRefreshTokenFamily = `
+		UPDATE refresh_tokens
+		SET revoked = true, revoked_at = NOW()
+		WHERE user_id = $1 AND family_id = $2 AND revoked = false
+		RETURNING token_pair_id::text`
+
+	queryListRevokedPairs = `
+		SELECT rt.token_pair_id::text, rt.revoked_at,
+		       rt.revoked_at + make_interval(secs => COALESCE(c.access_token_ttl, $1))
+		FROM refresh_tokens rt
+		LEFT JOIN oauth_clients c ON c.id = rt.client_id
+		WHERE rt.revoked = true
+		AND rt.revoked_at + make_interval(secs => COALESCE(c.access_token_ttl, $1)) > NOW()`
+
+	queryRevokeSession = `
+		UPDATE refresh_tokens
+		SET revoked = true, revoked_at = NOW()
+		WHERE user_id = $1
+		AND revoked = false
+		AND family_id = (SELECT family_id FROM refresh_tokens WHERE user_id = $1 AND token_pair_id = $2)
+		RETURNING token_pair_id::text`
+
+	queryRevokeAllSessions = `
+		UPDATE refresh_tokens
+		SET revoked = true, revoked_at = NOW()
+		WHERE user_id = $1
+		AND revoked = false
+		AND family_id IS DISTINCT FROM (SELECT family_id FROM refresh_tokens WHERE user_id = $1 AND token_pair_id = $2::uuid)
+		RETURNING token_pair_id::text`
+
+	querySaveAuditEvent = `
+		INSERT INTO audit_events (user_id, event_type, ip, user_agent, details)
 		VALUES ($1, $2, $3, $4, $5)`
 )
+
+const (
+	queryCreateUser = `
+		INSERT INTO users (email, password_hash)
+		VALUES ($1, $2)
+		RETURNING id::text, created_at`
+
+	queryFindUserByID = `
+		SELECT id::text, email, password_hash, created_at
+		FROM users
+		WHERE id = $1`
+
+	queryFindUserByEmail = `
+		SELECT id::text, email, password_hash, created_at
+		FROM users
+		WHERE lower(email) = lower($1)`
+)
+
+const (
+	queryFindTOTP = `
+		SELECT user_id::text, secret, confirmed_at, last_used_step
+		FROM user_totp
+		WHERE user_id = $1`
+
+	querySaveTOTPSecret = `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+		WHERE user_totp.confirmed_at IS NULL`
+
+	queryConfirmTOTP = `
+		UPDATE user_totp
+		SET confirmed_at = NOW(), last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NULL`
+
+	queryUseTOTPStep = `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2`
+
+	queryDeleteRecoveryCodes = `
+		DELETE FROM recovery_codes
+		WHERE user_id = $1`
+
+	querySaveRecoveryCode = `
+		INSERT INTO recovery_codes (user_id, code_hash)
+		VALUES ($1, $2)`
+
+	queryUseRecoveryCode = `
+		UPDATE recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+)
+
+const (
+	webAuthnCredentialColumns = `
+		id, user_id::text, public_key, alg, sign_count, COALESCE(aaguid, ''::bytea), name, cloned, created_at, last_used_at`
+
+	queryDeleteExpiredWebAuthnChallenges = `
+		DELETE FROM webauthn_challenges
+		WHERE expires_at < NOW()`
+
+	querySaveWebAuthnChallenge = `
+		INSERT INTO webauthn_challenges (user_id, ceremony, challenge, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id::text`
+
+	queryConsumeWebAuthnChallenge = `
+		DELETE FROM webauthn_challenges
+		WHERE id = $1 AND ceremony = $2 AND expires_at > NOW()
+		RETURNING id::text, COALESCE(user_id::text, ''), ceremony, challenge, expires_at`
+
+	queryListWebAuthnCredentials = `
+		SELECT` + webAuthnCredentialColumns + `
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	queryFindWebAuthnCredential = `
+		SELECT` + webAuthnCredentialColumns + `
+		FROM webauthn_credentials
+		WHERE id = $1`
+
+	querySaveWebAuthnCredential = `
+		INSERT INTO webauthn_credentials (id, user_id, public_key, alg, sign_count, aaguid, name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at`
+
+	queryUpdateWebAuthnSignCount = `
+		UPDATE webauthn_credentials
+		SET sign_count = $3, last_used_at = NOW()
+		WHERE id = $1 AND sign_count = $2 AND cloned = false`
+
+	queryMarkWebAuthnCredentialCloned = `
+		UPDATE webauthn_credentials
+		SET cloned = true
+		WHERE id = $1`
+)
+
+const (
+	querySaveAuthorizationCode = `
+		INSERT INTO authorization_codes (code_hash, client_id, user_id, redirect_uri, code_challenge, scope, nonce, amr, auth_time, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8::text[], '{}'), $9, $10)`
+
+	queryLockAuthorizationCode = `
+		SELECT code_hash, client_id, user_id::text, redirect_uri, code_challenge, scope, nonce, amr,
+		       auth_time, expires_at, used_at, COALESCE(family_id::text, '')
+		FROM authorization_codes
+		WHERE code_hash = $1
+		FOR UPDATE`
+
+	queryUseAuthorizationCode = `
+		UPDATE authorization_codes
+		SET used_at = NOW(), family_id = $2
+		WHERE code_hash = $1`
+
+	queryDeleteExpiredAuthorizationCodes = `
+		DELETE FROM authorization_codes
+		WHERE expires_at < NOW() - INTERVAL '1 day'`
+)
+
+const (
+	oauthClientColumns = `
+		id, name, COALESCE(secret_hash, ''), redirect_uris, grant_types, scopes, audiences,
+		COALESCE(access_token_ttl, 0), COALESCE(refresh_token_ttl, 0), created_at`
+
+	queryCreateOAuthClient = `
+		INSERT INTO oauth_clients (id, name, secret_hash, redirect_uris, grant_types, scopes, audiences, access_token_ttl, refresh_token_ttl)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, NULLIF($8, 0), NULLIF($9, 0))
+		RETURNING created_at`
+
+	queryFindOAuthClient = `
+		SELECT` + oauthClientColumns + `
+		FROM oauth_clients
+		WHERE id = $1`
+
+	queryListOAuthClients = `
+		SELECT` + oauthClientColumns + `
+		FROM oauth_clients
+		ORDER BY id`
+
+	queryMaxOAuthClientAccessTTL = `
+		SELECT COALESCE(MAX(access_token_ttl), 0)
+		FROM oauth_clients`
+)
+
+const (
+	roleColumns = `
+		r.name, r.description,
+		COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}'),
+		r.created_at, r.updated_at`
+
+	queryListRoles = `
+		SELECT` + roleColumns + `
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_name = r.name
+		GROUP BY r.name
+		ORDER BY r.name`
+
+	queryFindRole = `
+		SELECT` + roleColumns + `
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_name = r.name
+		WHERE r.name = $1
+		GROUP BY r.name`
+
+	queryCreateRole = `
+		INSERT INTO roles (name, description)
+		VALUES ($1, $2)
+		RETURNING created_at, updated_at`
+
+	queryUpdateRole = `
+		UPDATE roles
+		SET description = $2, updated_at = NOW()
+		WHERE name = $1
+		RETURNING created_at, updated_at`
+
+	queryDeleteRolePermissions = `
+		DELETE FROM role_permissions
+		WHERE role_name = $1`
+
+	querySaveRolePermissions = `
+		INSERT INTO role_permissions (role_name, permission)
+		SELECT $1, unnest($2::text[])`
+
+	queryDeleteRole = `
+		DELETE FROM roles
+		WHERE name = $1`
+
+	queryAssignRole = `
+		INSERT INTO user_roles (user_id, role_name)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`
+
+	queryUnassignRole = `
+		DELETE FROM user_roles
+		WHERE user_id = $1 AND role_name = $2`
+
+	queryListUserRoles = `
+		SELECT role_name
+		FROM user_roles
+		WHERE user_id = $1
+		ORDER BY role_name`
+
+	queryRolesHavePermission = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM role_permissions
+			WHERE role_name = ANY ($1) AND permission = $2
+		)`
+)
+
+const (
+	queryInitRateLimitBucket = `
+		INSERT INTO rate_limits (key)
+		VALUES ($1)
+		ON CONFLICT DO NOTHING`
+
+	queryLockRateLimitBucket = `
+		SELECT tokens, updated_at, locked_until
+		FROM rate_limits
+		WHERE key = $1
+		FOR UPDATE`
+
+	queryUpdateRateLimitBucket = `
+		UPDATE rate_limits
+		SET tokens = $2, updated_at = $3, locked_until = $4
+		WHERE key = $1`
+
+	queryFindRateLimitBucket = `
+		SELECT tokens, updated_at, locked_until
+		FROM rate_limits
+		WHERE key = $1`
+
+	queryDeleteStaleRateLimitBuckets = `
+		DELETE FROM rate_limits
+		WHERE (updated_at IS NULL OR updated_at < $1)
+		  AND (locked_until IS NULL OR locked_until < $1)`
+)
+
+const (
+	querySaveOutboxEvent = `
+		INSERT INTO outbox (event_type, payload)
+		VALUES ($1, $2)`
+
+	// queryClaimOutboxEvents leases due events: their next attempt is pushed
+	// past the lease so that other instances skip them meanwhile, and an
+	// instance dying mid-delivery only delays them.
+	queryClaimOutboxEvents = `
+		UPDATE outbox
+		SET attempts = attempts + 1, next_attempt_at = NOW() + $2::interval
+		WHERE id IN (
+			SELECT id
+			FROM outbox
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at`
+
+	queryMarkOutboxEventDelivered = `
+		UPDATE outbox
+		SET status = 'delivered', delivered_at = NOW(), last_error = ''
+		WHERE id = $1`
+
+	queryRetryOutboxEvent = `
+		UPDATE outbox
+		SET next_attempt_at = $2, last_error = $3
+		WHERE id = $1`
+
+	queryDeadLetterOutboxEvent = `
+		UPDATE outbox
+		SET status = 'dead', last_error = $2
+		WHERE id = $1`
+
+	queryListOutboxEvents = `
+		SELECT id, event_type, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at
+		FROM outbox
+		WHERE status = $1
+		ORDER BY id DESC
+		LIMIT $2`
+
+	queryFindOutboxEventStatus = `
+		SELECT status
+		FROM outbox
+		WHERE id = $1`
+
+	queryReplayOutboxEvent = `
+		UPDATE outbox
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), last_error = ''
+		WHERE id = $1 AND status = 'dead'`
+
+	queryReplayDeadOutboxEvents = `
+		UPDATE outbox
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), last_error = ''
+		WHERE status = 'dead'`
+)
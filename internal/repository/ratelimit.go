@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// UpdateRateLimitBucket applies update to the bucket under a row lock, so that
+// concurrent requests on every replica see each other's tokens.
+func (r Repository) UpdateRateLimitBucket(ctx context.Context, key string, update func(models.RateLimitBucket) models.RateLimitBucket) (models.RateLimitBucket, error) {
+	var bucket models.RateLimitBucket
+
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, queryInitRateLimitBucket, key); err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		current, err := scanRateLimitBucket(key, tx.QueryRow(ctx, queryLockRateLimitBucket, key))
+		if err != nil {
+			return fmt.Errorf("tx.QueryRow: %w", err)
+		}
+
+		bucket = update(current)
+		if _, err = tx.Exec(ctx, queryUpdateRateLimitBucket,
+			key, bucket.Tokens, nullTime(bucket.UpdatedAt), nullTime(bucket.LockedUntil)); err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return models.RateLimitBucket{}, fmt.Errorf("update rate limit bucket: %w", err)
+	}
+
+	return bucket, nil
+}
+
+// FindRateLimitBucket returns an empty bucket when the key was never used.
+func (r Repository) FindRateLimitBucket(ctx context.Context, key string) (models.RateLimitBucket, error) {
+	bucket, err := scanRateLimitBucket(key, r.conn.QueryRow(ctx, queryFindRateLimitBucket, key))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.RateLimitBucket{Key: key}, nil
+	}
+	if err != nil {
+		return models.RateLimitBucket{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return bucket, nil
+}
+
+func (r Repository) DeleteStaleRateLimitBuckets(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.conn.Exec(ctx, queryDeleteStaleRateLimitBuckets, before)
+	if err != nil {
+		return 0, fmt.Errorf("r.conn.Exec: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func scanRateLimitBucket(key string, row pgx.Row) (models.RateLimitBucket, error) {
+	bucket := models.RateLimitBucket{Key: key}
+	var updatedAt, lockedUntil *time.Time
+
+	if err := row.Scan(&bucket.Tokens, &updatedAt, &lockedUntil); err != nil {
+		return models.RateLimitBucket{}, err
+	}
+	if updatedAt != nil {
+		bucket.UpdatedAt = *updatedAt
+	}
+	if lockedUntil != nil {
+		bucket.LockedUntil = *lockedUntil
+	}
+
+	return bucket, nil
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -4,12 +4,64 @@ import (
 	"auth-service/models"
 	"context"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
 )
 
 type RepositoryI interface {
 	SaveRefreshToken(ctx context.Context, token models.RefreshToken) error
 	FindRefreshTokenByPairID(ctx context.Context, userID, pairID string) (models.RefreshToken, error)
+	FindRefreshTokenBySelector(ctx context.Context, selector string) (models.RefreshToken, error)
+	FindRefreshTokenByLookup(ctx context.Context, lookup string) (models.RefreshToken, error)
+	ListActiveRefreshTokens(ctx context.Context, userID string, beforeID, limit int) ([]models.RefreshToken, error)
 	RevokeRefreshTokenByPairID(ctx context.Context, userID, pairID string) error
+	RotateRefreshToken(ctx context.Context, userID, pairID string, token models.RefreshToken, events []models.OutboxEvent) error
+	RevokeRefreshTokenFamily(ctx context.Context, userID, familyID string, events []models.OutboxEvent) ([]string, error)
+	ListRevokedPairs(ctx context.Context, defaultTTL time.Duration) ([]models.RevokedPair, error)
+	ListenRevocations(ctx context.Context, ready func(ctx context.Context) error, handle func(models.RevocationEvent)) error
+	RevokeSession(ctx context.Context, userID, pairID string, event models.AuditEvent) ([]string, error)
+	RevokeAllSessions(ctx context.Context, userID, exceptPairID string, event models.AuditEvent) ([]string, error)
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+	CreateUser(ctx context.Context, user models.User) (models.User, error)
+	FindUserByID(ctx context.Context, userID string) (models.User, error)
+	FindUserByEmail(ctx context.Context, email string) (models.User, error)
+	FindTOTP(ctx context.Context, userID string) (models.TOTP, error)
+	SaveTOTPSecret(ctx context.Context, userID, secret string) error
+	ConfirmTOTP(ctx context.Context, userID string, step int64, codeHashes []string, event models.AuditEvent) error
+	UseTOTPStep(ctx context.Context, userID string, step int64) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) error
+	SaveWebAuthnChallenge(ctx context.Context, challenge models.WebAuthnChallenge) (string, error)
+	ConsumeWebAuthnChallenge(ctx context.Context, id, ceremony string) (models.WebAuthnChallenge, error)
+	ListWebAuthnCredentials(ctx context.Context, userID string) ([]models.WebAuthnCredential, error)
+	FindWebAuthnCredential(ctx context.Context, id []byte) (models.WebAuthnCredential, error)
+	SaveWebAuthnCredential(ctx context.Context, credential models.WebAuthnCredential, event models.AuditEvent) (models.WebAuthnCredential, error)
+	UpdateWebAuthnSignCount(ctx context.Context, id []byte, previous, next uint32) error
+	MarkWebAuthnCredentialCloned(ctx context.Context, id []byte, event models.AuditEvent) error
+	CreateOAuthClient(ctx context.Context, client models.OAuthClient) (models.OAuthClient, error)
+	FindOAuthClient(ctx context.Context, clientID string) (models.OAuthClient, error)
+	ListOAuthClients(ctx context.Context) ([]models.OAuthClient, error)
+	MaxOAuthClientAccessTTL(ctx context.Context) (time.Duration, error)
+	SaveAuthorizationCode(ctx context.Context, code models.AuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash, familyID string) (models.AuthorizationCode, error)
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	FindRole(ctx context.Context, name string) (models.Role, error)
+	CreateRole(ctx context.Context, role models.Role, event models.AuditEvent) (models.Role, error)
+	UpdateRole(ctx context.Context, role models.Role, event models.AuditEvent) (models.Role, error)
+	DeleteRole(ctx context.Context, name string, event models.AuditEvent) error
+	AssignRole(ctx context.Context, userID, role string, event models.AuditEvent) error
+	UnassignRole(ctx context.Context, userID, role string, event models.AuditEvent) error
+	ListUserRoles(ctx context.Context, userID string) ([]string, error)
+	RolesHavePermission(ctx context.Context, roles []string, permission string) (bool, error)
+	UpdateRateLimitBucket(ctx context.Context, key string, update func(models.RateLimitBucket) models.RateLimitBucket) (models.RateLimitBucket, error)
+	FindRateLimitBucket(ctx context.Context, key string) (models.RateLimitBucket, error)
+	DeleteStaleRateLimitBuckets(ctx context.Context, before time.Time) (int64, error)
+	SaveOutboxEvents(ctx context.Context, events []models.OutboxEvent) error
+	ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	MarkOutboxEventDelivered(ctx context.Context, id int64) error
+	RetryOutboxEvent(ctx context.Context, id int64, at time.Time, lastError string) error
+	DeadLetterOutboxEvent(ctx context.Context, id int64, lastError string) error
+	ListOutboxEvents(ctx context.Context, status string, limit int) ([]models.OutboxEvent, error)
+	ReplayOutboxEvent(ctx context.Context, id int64, event models.AuditEvent) error
+	ReplayDeadOutboxEvents(ctx context.Context, event models.AuditEvent) (int64, error)
 }
 
 type Repository struct {
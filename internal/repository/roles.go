@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// userRolesRoleFK is the constraint violated when a role assigned to a user
+// does not exist, as opposed to the user.
+const userRolesRoleFK = "user_roles_role_name_fkey"
+
+func (r Repository) ListRoles(ctx context.Context) ([]models.Role, error) {
+	rows, err := r.conn.Query(ctx, queryListRoles)
+	if err != nil {
+		return nil, fmt.Errorf("r.conn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r Repository) FindRole(ctx context.Context, name string) (models.Role, error) {
+	role, err := scanRole(r.conn.QueryRow(ctx, queryFindRole, name))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.Role{}, apperrors.ErrRoleNotFound
+	}
+	if err != nil {
+		return models.Role{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return role, nil
+}
+
+func (r Repository) CreateRole(ctx context.Context, role models.Role, event models.AuditEvent) (models.Role, error) {
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, queryCreateRole, role.Name, role.Description).Scan(&role.CreatedAt, &role.UpdatedAt)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return apperrors.ErrRoleExists
+		}
+		if err != nil {
+			return fmt.Errorf("tx.QueryRow: %w", err)
+		}
+
+		if err = saveRolePermissions(ctx, tx, role); err != nil {
+			return err
+		}
+
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return models.Role{}, fmt.Errorf("create role: %w", err)
+	}
+
+	return role, nil
+}
+
+// UpdateRole replaces the description and the permissions of the role.
+func (r Repository) UpdateRole(ctx context.Context, role models.Role, event models.AuditEvent) (models.Role, error) {
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, queryUpdateRole, role.Name, role.Description).Scan(&role.CreatedAt, &role.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apperrors.ErrRoleNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("tx.QueryRow: %w", err)
+		}
+
+		if _, err = tx.Exec(ctx, queryDeleteRolePermissions, role.Name); err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+		if err = saveRolePermissions(ctx, tx, role); err != nil {
+			return err
+		}
+
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return models.Role{}, fmt.Errorf("update role: %w", err)
+	}
+
+	return role, nil
+}
+
+func (r Repository) DeleteRole(ctx context.Context, name string, event models.AuditEvent) error {
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryDeleteRole, name)
+		if err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return apperrors.ErrRoleNotFound
+		}
+
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("delete role: %w", err)
+	}
+
+	return nil
+}
+
+// AssignRole grants the role to the user. Assigning it again is a no-op.
+func (r Repository) AssignRole(ctx context.Context, userID, role string, event models.AuditEvent) error {
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, queryAssignRole, userID, role)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			if pgErr.ConstraintName == userRolesRoleFK {
+				return apperrors.ErrRoleNotFound
+			}
+			return apperrors.ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("assign role: %w", err)
+	}
+
+	return nil
+}
+
+func (r Repository) UnassignRole(ctx context.Context, userID, role string, event models.AuditEvent) error {
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, queryUnassignRole, userID, role)
+		if err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return apperrors.ErrRoleNotAssigned
+		}
+
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("unassign role: %w", err)
+	}
+
+	return nil
+}
+
+func (r Repository) ListUserRoles(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.conn.Query(ctx, queryListUserRoles, userID)
+	if err != nil {
+		return nil, fmt.Errorf("r.conn.Query: %w", err)
+	}
+
+	roles, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("pgx.CollectRows: %w", err)
+	}
+
+	return roles, nil
+}
+
+func (r Repository) RolesHavePermission(ctx context.Context, roles []string, permission string) (bool, error) {
+	var ok bool
+	if err := r.conn.QueryRow(ctx, queryRolesHavePermission, roles, permission).Scan(&ok); err != nil {
+		return false, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return ok, nil
+}
+
+func saveRolePermissions(ctx context.Context, tx pgx.Tx, role models.Role) error {
+	_, err := tx.Exec(ctx, querySaveRolePermissions, role.Name, nonNil(role.Permissions))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+		return apperrors.ErrUnknownPermission
+	}
+	if err != nil {
+		return fmt.Errorf("tx.Exec: %w", err)
+	}
+
+	return nil
+}
+
+func scanRole(row pgx.Row) (models.Role, error) {
+	var role models.Role
+	err := row.Scan(&role.Name, &role.Description, &role.Permissions, &role.CreatedAt, &role.UpdatedAt)
+	return role, err
+}
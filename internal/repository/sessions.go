@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/models"
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+// RevokeSession revokes every token of the rotation chain the pair belongs to
+// and records the audit event in the same transaction.
+func (r Repository) RevokeSession(ctx context.Context, userID, pairID string, event models.AuditEvent) ([]string, error) {
+	if event.Details == nil {
+		event.Details = map[string]interface{}{}
+	}
+
+	var pairIDs []string
+
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		var err error
+		pairIDs, err = collectPairIDs(tx.Query(ctx, queryRevokeSession, userID, pairID))
+		if err != nil {
+			return err
+		}
+		if len(pairIDs) == 0 {
+			return apperrors.ErrSessionNotFound
+		}
+
+		event.Details["revoked_pair_ids"] = pairIDs
+		if err = saveAuditEvent(ctx, tx, event); err != nil {
+			return err
+		}
+
+		return notifyRevoked(ctx, tx, pairIDs)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("revoke session: %w", err)
+	}
+
+	return pairIDs, nil
+}
+
+// RevokeAllSessions revokes every active token of the user. When exceptPairID
+// is set, the rotation chain of that pair is kept.
+func (r Repository) RevokeAllSessions(ctx context.Context, userID, exceptPairID string, event models.AuditEvent) ([]string, error) {
+	var except interface{}
+	if exceptPairID != "" {
+		except = exceptPairID
+	}
+	if event.Details == nil {
+		event.Details = map[string]interface{}{}
+	}
+
+	var pairIDs []string
+
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		var err error
+		pairIDs, err = collectPairIDs(tx.Query(ctx, queryRevokeAllSessions, userID, except))
+		if err != nil {
+			return err
+		}
+
+		event.Details["revoked_pair_ids"] = pairIDs
+		if err = saveAuditEvent(ctx, tx, event); err != nil {
+			return err
+		}
+
+		return notifyRevoked(ctx, tx, pairIDs)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("revoke all sessions: %w", err)
+	}
+
+	return pairIDs, nil
+}
+
+func collectPairIDs(rows pgx.Rows, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	pairIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	if pairIDs == nil {
+		pairIDs = []string{}
+	}
+
+	return pairIDs, nil
+}
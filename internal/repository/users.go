@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+func (r Repository) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	err := r.conn.QueryRow(ctx, queryCreateUser, user.Email, user.PasswordHash).Scan(&user.ID, &user.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return models.User{}, apperrors.ErrUserExists
+	}
+	if err != nil {
+		return models.User{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r Repository) FindUserByID(ctx context.Context, userID string) (models.User, error) {
+	var user models.User
+	err := r.conn.QueryRow(ctx, queryFindUserByID, userID).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, apperrors.ErrUserNotFound
+	}
+	if err != nil {
+		return models.User{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r Repository) FindUserByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := r.conn.QueryRow(ctx, queryFindUserByEmail, email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.User{}, apperrors.ErrUserNotFound
+	}
+	if err != nil {
+		return models.User{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return user, nil
+}
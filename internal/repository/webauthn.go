@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SaveWebAuthnChallenge stores a ceremony challenge and drops the expired ones.
+func (r Repository) SaveWebAuthnChallenge(ctx context.Context, challenge models.WebAuthnChallenge) (string, error) {
+	var userID interface{}
+	if challenge.UserID != "" {
+		userID = challenge.UserID
+	}
+
+	var id string
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, queryDeleteExpiredWebAuthnChallenges); err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		return tx.QueryRow(ctx, querySaveWebAuthnChallenge,
+			userID, challenge.Ceremony, challenge.Challenge, challenge.ExpiresAt).Scan(&id)
+	})
+	if err != nil {
+		return "", fmt.Errorf("save webauthn challenge: %w", err)
+	}
+
+	return id, nil
+}
+
+// ConsumeWebAuthnChallenge deletes and returns the challenge, so every
+// challenge can be answered only once.
+func (r Repository) ConsumeWebAuthnChallenge(ctx context.Context, id, ceremony string) (models.WebAuthnChallenge, error) {
+	var challenge models.WebAuthnChallenge
+	err := r.conn.QueryRow(ctx, queryConsumeWebAuthnChallenge, id, ceremony).Scan(
+		&challenge.ID, &challenge.UserID, &challenge.Ceremony, &challenge.Challenge, &challenge.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.WebAuthnChallenge{}, apperrors.ErrChallengeNotFound
+	}
+	if err != nil {
+		return models.WebAuthnChallenge{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return challenge, nil
+}
+
+func (r Repository) ListWebAuthnCredentials(ctx context.Context, userID string) ([]models.WebAuthnCredential, error) {
+	rows, err := r.conn.Query(ctx, queryListWebAuthnCredentials, userID)
+	if err != nil {
+		return nil, fmt.Errorf("r.conn.Query: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []models.WebAuthnCredential
+	for rows.Next() {
+		credential, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, fmt.Errorf("rows.Scan: %w", err)
+		}
+		credentials = append(credentials, credential)
+	}
+
+	return credentials, rows.Err()
+}
+
+func (r Repository) FindWebAuthnCredential(ctx context.Context, id []byte) (models.WebAuthnCredential, error) {
+	credential, err := scanWebAuthnCredential(r.conn.QueryRow(ctx, queryFindWebAuthnCredential, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.WebAuthnCredential{}, apperrors.ErrCredentialNotFound
+	}
+	if err != nil {
+		return models.WebAuthnCredential{}, fmt.Errorf("r.conn.QueryRow: %w", err)
+	}
+
+	return credential, nil
+}
+
+func (r Repository) SaveWebAuthnCredential(ctx context.Context, credential models.WebAuthnCredential, event models.AuditEvent) (models.WebAuthnCredential, error) {
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, querySaveWebAuthnCredential,
+			credential.ID, credential.UserID, credential.PublicKey, credential.Alg,
+			int64(credential.SignCount), credential.AAGUID, credential.Name).Scan(&credential.CreatedAt)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return apperrors.ErrCredentialExists
+		}
+		if err != nil {
+			return fmt.Errorf("tx.QueryRow: %w", err)
+		}
+
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return models.WebAuthnCredential{}, fmt.Errorf("save webauthn credential: %w", err)
+	}
+
+	return credential, nil
+}
+
+// UpdateWebAuthnSignCount moves the counter from previous to next. It fails
+// when another assertion has already moved it, which is treated as a clone.
+func (r Repository) UpdateWebAuthnSignCount(ctx context.Context, id []byte, previous, next uint32) error {
+	tag, err := r.conn.Exec(ctx, queryUpdateWebAuthnSignCount, id, int64(previous), int64(next))
+	if err != nil {
+		return fmt.Errorf("r.conn.Exec: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return apperrors.ErrCredentialCloned
+	}
+
+	return nil
+}
+
+func (r Repository) MarkWebAuthnCredentialCloned(ctx context.Context, id []byte, event models.AuditEvent) error {
+	err := pgx.BeginFunc(ctx, r.conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, queryMarkWebAuthnCredentialCloned, id); err != nil {
+			return fmt.Errorf("tx.Exec: %w", err)
+		}
+
+		return saveAuditEvent(ctx, tx, event)
+	})
+	if err != nil {
+		return fmt.Errorf("mark webauthn credential cloned: %w", err)
+	}
+
+	return nil
+}
+
+func scanWebAuthnCredential(row pgx.Row) (models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	var signCount int64
+
+	err := row.Scan(&credential.ID, &credential.UserID, &credential.PublicKey, &credential.Alg, &signCount,
+		&credential.AAGUID, &credential.Name, &credential.Cloned, &credential.CreatedAt, &credential.LastUsedAt)
+	if err != nil {
+		return models.WebAuthnCredential{}, err
+	}
+	credential.SignCount = uint32(signCount)
+
+	return credential, nil
+}
@@ -0,0 +1,77 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Denylist keeps the token_pair_ids revoked while access tokens issued for
+// them may still be unexpired. Entries drop out once that window has passed.
+type Denylist struct {
+	mu      sync.RWMutex
+	entries map[string]time.Time
+}
+
+func NewDenylist() *Denylist {
+	return &Denylist{
+		entries: make(map[string]time.Time),
+	}
+}
+
+func (d *Denylist) Add(pairID string, until time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if current, ok := d.entries[pairID]; !ok || until.After(current) {
+		d.entries[pairID] = until
+	}
+}
+
+func (d *Denylist) IsRevoked(pairID string) bool {
+	d.mu.RLock()
+	until, ok := d.entries[pairID]
+	d.mu.RUnlock()
+
+	return ok && time.Now().Before(until)
+}
+
+// Replace swaps the whole content of the denylist, used after a full reload.
+func (d *Denylist) Replace(entries map[string]time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries = entries
+}
+
+func (d *Denylist) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return len(d.entries)
+}
+
+func (d *Denylist) purge(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for pairID, until := range d.entries {
+		if !now.Before(until) {
+			delete(d.entries, pairID)
+		}
+	}
+}
+
+func (d *Denylist) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.purge(now)
+		}
+	}
+}
@@ -3,43 +3,93 @@ package service
 import (
 	"auth-service/internal/apperrors"
 	"auth-service/internal/auth"
-	"auth-service/internal/utils"
 	"auth-service/models"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/gookit/slog"
+	"time"
 )
 
-func (s Service) GenerateTokens(ctx context.Context, userID, ip, userAgent string) (models.TokensResponse, error) {
+func (s Service) GenerateTokens(ctx context.Context, grant models.Grant) (models.TokensResponse, error) {
+	if grant.AuthTime.IsZero() {
+		grant.AuthTime = time.Now()
+	}
+	return s.issueTokens(ctx, grant, uuid.New().String(), nil)
+}
+
+func (s Service) issueTokens(ctx context.Context, grant models.Grant, familyID string, parentID *int) (models.TokensResponse, error) {
+	tokens, refreshToken, err := s.newTokens(ctx, grant, familyID, parentID)
+	if err != nil {
+		return models.TokensResponse{}, err
+	}
+
+	if err = s.repo.SaveRefreshToken(ctx, refreshToken); err != nil {
+		return models.TokensResponse{}, fmt.Errorf("save refresh token: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// newTokens signs the tokens of a grant and returns the refresh token row
+// for the caller to save.
+func (s Service) newTokens(ctx context.Context, grant models.Grant, familyID string, parentID *int) (models.TokensResponse, models.RefreshToken, error) {
 	pairID := uuid.New().String()
 
-	tokenBase64, hash, err := auth.GenerateRefreshToken()
+	accessTTL, refreshTTL := s.cfg.JWT.AccessTTL, s.cfg.JWT.RefreshTTL
+	if grant.AccessTTL > 0 {
+		accessTTL = grant.AccessTTL
+	}
+	if grant.RefreshTTL > 0 {
+		refreshTTL = grant.RefreshTTL
+	}
+
+	expiresAt := time.Now().Add(refreshTTL)
+	if sessionEnd := grant.AuthTime.Add(s.cfg.JWT.SessionMaxLifetime); sessionEnd.Before(expiresAt) {
+		expiresAt = sessionEnd
+	}
+
+	refresh, selector, verifierHash, err := auth.GenerateRefreshToken([]byte(s.cfg.JWT.RefreshHashKey))
 	if err != nil {
-		return models.TokensResponse{}, fmt.Errorf("generate refresh token: %w", err)
+		return models.TokensResponse{}, models.RefreshToken{}, fmt.Errorf("generate refresh token: %w", err)
+	}
+
+	if grant.Roles, err = s.userRoles(ctx, grant); err != nil {
+		return models.TokensResponse{}, models.RefreshToken{}, fmt.Errorf("list user roles: %w", err)
 	}
 
-	access, err := auth.GenerateAccessToken(userID, ip, userAgent, pairID)
+	access, err := auth.GenerateAccessToken(grant, pairID, accessTTL)
 	if err != nil {
-		return models.TokensResponse{}, fmt.Errorf("generate access token: %w", err)
+		return models.TokensResponse{}, models.RefreshToken{}, fmt.Errorf("generate access token: %w", err)
 	}
 
-	refreshToken := models.RefreshToken{
-		UserID:      userID,
-		TokenHash:   hash,
-		UserAgent:   userAgent,
-		IP:          ip,
-		TokenPairID: pairID,
+	idToken, err := s.issueIDToken(grant, access, accessTTL)
+	if err != nil {
+		return models.TokensResponse{}, models.RefreshToken{}, fmt.Errorf("generate id token: %w", err)
 	}
 
-	if err = s.repo.SaveRefreshToken(ctx, refreshToken); err != nil {
-		return models.TokensResponse{}, fmt.Errorf("save refresh token: %w", err)
+	refreshToken := models.RefreshToken{
+		UserID:       grant.UserID,
+		Selector:     selector,
+		VerifierHash: verifierHash,
+		UserAgent:    grant.UserAgent,
+		IP:           grant.IP,
+		AMR:          grant.AMR,
+		ClientID:     grant.ClientID,
+		Scope:        grant.Scope,
+		TokenPairID:  pairID,
+		FamilyID:     familyID,
+		ParentID:     parentID,
+		AuthTime:     grant.AuthTime,
+		ExpiresAt:    expiresAt,
 	}
 
 	return models.TokensResponse{
 		Access:  access,
-		Refresh: tokenBase64,
-	}, nil
+		Refresh: refresh,
+		IDToken: idToken,
+	}, refreshToken, nil
 }
 
 func (s Service) RefreshTokens(ctx context.Context, userID, access, refresh, userAgent, ip string) (models.TokensResponse, error) {
@@ -48,33 +98,70 @@ func (s Service) RefreshTokens(ctx context.Context, userID, access, refresh, use
 		return models.TokensResponse{}, fmt.Errorf("validate access token: %w", err)
 	}
 
+	if err = s.allowUser(ctx, userID); err != nil {
+		return models.TokensResponse{}, err
+	}
+
+	if err = s.checkLockout(ctx, userID); err != nil {
+		return models.TokensResponse{}, err
+	}
+
 	token, err := s.validateRefreshToken(ctx, userID, accessPairID, refresh)
+	if errors.Is(err, apperrors.ErrTokenReused) {
+		s.revokeReusedFamily(ctx, token, ip, userAgent)
+	}
+	if errors.Is(err, apperrors.ErrTokenIsNotFound) || errors.Is(err, apperrors.ErrInvalidToken) {
+		s.recordRefreshFailure(ctx, userID, ip, userAgent)
+	}
 	if err != nil {
 		return models.TokensResponse{}, fmt.Errorf("validate refresh token: %w", err)
 	}
 
-	if token.UserAgent != userAgent {
-		if revokeErr := s.repo.RevokeRefreshTokenByPairID(ctx, userID, accessPairID); revokeErr != nil {
-			slog.Error("failed to revoke token after user agent mismatch", "err", revokeErr)
-		}
-		slog.Error("user agent mismatch, user is deauthorized", "user_id", userID)
-		return models.TokensResponse{}, apperrors.ErrUserDeauthorized
-	}
+	return s.rotateSession(ctx, token, ip, userAgent)
+}
+
+// rotateSession replaces a validated refresh token and the access token of
+// its pair with a new pair of the same session, checking the client binding
+// first.
+func (s Service) rotateSession(ctx context.Context, token models.RefreshToken, ip, userAgent string) (models.TokensResponse, error) {
+	userID, pairID := token.UserID, token.TokenPairID
 
-	if ip != token.IP {
-		slog.Warn("authorization from new ip", "user_id", userID, "old_ip", token.IP, "new_ip", ip)
-		utils.NotifyNewIP(userID, token.IP, ip, userAgent)
+	events, err := s.checkBinding(ctx, token, pairID, ip, userAgent)
+	if err != nil {
+		return models.TokensResponse{}, err
 	}
 
-	if err = s.repo.RevokeRefreshTokenByPairID(ctx, userID, accessPairID); err != nil {
-		return models.TokensResponse{}, fmt.Errorf("revoke refresh token by pair id: %w", err)
+	grant := models.Grant{
+		UserID:    userID,
+		IP:        ip,
+		UserAgent: userAgent,
+		AMR:       token.AMR,
+		AuthTime:  token.AuthTime,
+		ClientID:  token.ClientID,
+		Scope:     token.Scope,
+	}
+	if token.ClientID != "" {
+		client, err := s.repo.FindOAuthClient(ctx, token.ClientID)
+		if errors.Is(err, apperrors.ErrClientNotFound) {
+			return models.TokensResponse{}, fmt.Errorf("client %q of the session was removed: %w", token.ClientID, apperrors.ErrInvalidToken)
+		}
+		if err != nil {
+			return models.TokensResponse{}, fmt.Errorf("find client: %w", err)
+		}
+		grant.AccessTTL, grant.RefreshTTL = client.AccessTokenTTL, client.RefreshTokenTTL
+		grant.Audience = s.accessAudience(client, token.Scope)
 	}
 
-	tokens, err := s.GenerateTokens(ctx, userID, ip, userAgent)
+	tokens, refreshToken, err := s.newTokens(ctx, grant, token.FamilyID, &token.ID)
 	if err != nil {
 		return models.TokensResponse{}, fmt.Errorf("generate new tokens: %w", err)
 	}
 
+	if err = s.repo.RotateRefreshToken(ctx, userID, pairID, refreshToken, events); err != nil {
+		return models.TokensResponse{}, fmt.Errorf("rotate refresh token: %w", err)
+	}
+	s.denyPairs(ctx, time.Now(), pairID)
+
 	return tokens, nil
 }
 
@@ -90,7 +177,7 @@ func (s Service) Logout(ctx context.Context, userID, accessToken string) error {
 		return apperrors.ErrInvalidToken
 	}
 
-	if err = s.repo.RevokeRefreshTokenByPairID(ctx, userID, pairID); err != nil {
+	if err = s.revokePair(ctx, userID, pairID); err != nil {
 		return fmt.Errorf("revoke refresh token: %w", err)
 	}
 
@@ -112,3 +199,7 @@ func (s Service) IsRefreshTokenRevoked(ctx context.Context, userID, pairID strin
 	}
 	return token.Revoked, nil
 }
+
+func (s Service) PublicKeys() (models.JWKSet, error) {
+	return auth.PublicJWKS()
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/binding"
+	"auth-service/models"
+	"context"
+	"github.com/gookit/slog"
+)
+
+// checkBinding compares the client presenting a refresh token with the one it
+// was issued to. deny revokes the session, step_up keeps it but makes the user
+// log in again, notify only reports the change: the returned webhook events
+// are queued together with the rotation.
+func (s Service) checkBinding(ctx context.Context, token models.RefreshToken, pairID, ip, userAgent string) ([]models.OutboxEvent, error) {
+	bound := binding.Client{UserAgent: token.UserAgent, IP: token.IP}
+	current := binding.Client{UserAgent: userAgent, IP: ip}
+
+	result := s.binding.Evaluate(bound, current)
+	if len(result.Violations) == 0 {
+		return nil, nil
+	}
+
+	checks := make([]string, 0, len(result.Violations))
+	for _, violation := range result.Violations {
+		checks = append(checks, violation.Check)
+	}
+
+	switch result.Action {
+	case binding.ActionDeny:
+		if err := s.revokePair(ctx, token.UserID, pairID); err != nil {
+			slog.Error("failed to revoke token after binding mismatch", "err", err)
+		}
+		slog.Error("client binding mismatch, user is deauthorized", "user_id", token.UserID, "checks", checks)
+		s.auditBindingMismatch(ctx, token, ip, userAgent, result.Action, checks)
+		return nil, apperrors.ErrUserDeauthorized
+	case binding.ActionStepUp:
+		slog.Warn("client binding mismatch, re-authentication required", "user_id", token.UserID, "checks", checks)
+		s.auditBindingMismatch(ctx, token, ip, userAgent, result.Action, checks)
+		return nil, apperrors.ErrStepUpRequired
+	}
+
+	var events []models.OutboxEvent
+	for _, violation := range result.Violations {
+		switch violation.Check {
+		case binding.CheckIP:
+			slog.Warn("authorization from new ip", "user_id", token.UserID, "old_ip", token.IP, "new_ip", ip)
+			events = append(events, s.webhookEvent(models.WebhookNewIP, map[string]interface{}{
+				"user_id":    token.UserID,
+				"old_ip":     token.IP,
+				"new_ip":     ip,
+				"user_agent": userAgent,
+			})...)
+		case binding.CheckUserAgent:
+			slog.Warn("authorization from new user agent", "user_id", token.UserID, "old_user_agent", token.UserAgent, "new_user_agent", userAgent)
+			events = append(events, s.webhookEvent(models.WebhookNewUserAgent, map[string]interface{}{
+				"user_id":        token.UserID,
+				"old_user_agent": token.UserAgent,
+				"new_user_agent": userAgent,
+				"ip":             ip,
+			})...)
+		}
+	}
+
+	return events, nil
+}
+
+func (s Service) auditBindingMismatch(ctx context.Context, token models.RefreshToken, ip, userAgent, action string, checks []string) {
+	s.audit(ctx, models.AuditEvent{
+		UserID:    token.UserID,
+		Type:      models.AuditBindingMismatch,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"token_pair_id":   token.TokenPairID,
+			"action":          action,
+			"checks":          checks,
+			"bound_ip":        token.IP,
+			"bound_useragent": token.UserAgent,
+		},
+	})
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"auth-service/models"
+	"context"
+	"github.com/gookit/slog"
+	"time"
+)
+
+// revokeReusedFamily is called when an already rotated refresh token is
+// presented again: either it or its successor is held by someone else, so
+// every session of the rotation chain is revoked.
+func (s Service) revokeReusedFamily(ctx context.Context, token models.RefreshToken, ip, userAgent string) {
+	events := s.webhookEvent(models.WebhookRefreshTokenReuse, map[string]interface{}{
+		"user_id":    token.UserID,
+		"family_id":  token.FamilyID,
+		"ip":         ip,
+		"user_agent": userAgent,
+	})
+
+	pairIDs, err := s.repo.RevokeRefreshTokenFamily(ctx, token.UserID, token.FamilyID, events)
+	if err != nil {
+		slog.Error("failed to revoke refresh token family", "family_id", token.FamilyID, "err", err)
+	}
+	s.denyPairs(ctx, time.Now(), pairIDs...)
+	revoked := len(pairIDs)
+
+	slog.Warn("refresh token reuse detected, token family revoked",
+		"user_id", token.UserID, "family_id", token.FamilyID, "revoked", revoked)
+
+	s.audit(ctx, models.AuditEvent{
+		UserID:    token.UserID,
+		Type:      models.AuditRefreshTokenReuse,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"family_id":      token.FamilyID,
+			"token_pair_id":  token.TokenPairID,
+			"revoked_tokens": revoked,
+		},
+	})
+}
+
+func (s Service) audit(ctx context.Context, event models.AuditEvent) {
+	if err := s.repo.SaveAuditEvent(ctx, event); err != nil {
+		slog.Error("failed to save audit event", "type", event.Type, "err", err)
+	}
+}
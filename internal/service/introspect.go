@@ -0,0 +1,179 @@
+package service
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/auth"
+	"auth-service/models"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
+)
+
+const (
+	tokenTypeAccess  = "access_token"
+	tokenTypeRefresh = "refresh_token"
+)
+
+// AuthenticateClient authenticates a resource server calling /introspect.
+// Besides the registry it accepts the clients of INTROSPECTION_CLIENTS,
+// which predate it.
+func (s Service) AuthenticateClient(ctx context.Context, clientID, clientSecret string) error {
+	_, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if !errors.Is(err, apperrors.ErrInvalidClient) {
+		return err
+	}
+
+	secret, ok := s.cfg.Introspection.Clients[clientID]
+	if !ok || subtle.ConstantTimeCompare([]byte(secret), []byte(clientSecret)) != 1 {
+		return apperrors.ErrInvalidClient
+	}
+	return nil
+}
+
+// authenticateClient checks the secret of a confidential client of the registry.
+func (s Service) authenticateClient(ctx context.Context, clientID, clientSecret string) (models.OAuthClient, error) {
+	if clientID == "" || clientSecret == "" {
+		return models.OAuthClient{}, apperrors.ErrInvalidClient
+	}
+
+	client, err := s.repo.FindOAuthClient(ctx, clientID)
+	if errors.Is(err, apperrors.ErrClientNotFound) {
+		return models.OAuthClient{}, apperrors.ErrInvalidClient
+	}
+	if err != nil {
+		return models.OAuthClient{}, fmt.Errorf("find client: %w", err)
+	}
+
+	if !client.Confidential() {
+		return models.OAuthClient{}, apperrors.ErrInvalidClient
+	}
+
+	ok, err := auth.VerifyPassword(clientSecret, client.SecretHash)
+	if err != nil {
+		return models.OAuthClient{}, fmt.Errorf("verify client secret: %w", err)
+	}
+	if !ok {
+		return models.OAuthClient{}, apperrors.ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+func (s Service) Introspect(ctx context.Context, token, tokenTypeHint string) (models.IntrospectionResponse, error) {
+	lookups := []func(context.Context, string) (models.IntrospectionResponse, error){
+		s.introspectAccessToken, s.introspectRefreshToken,
+	}
+	if tokenTypeHint == tokenTypeRefresh {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		resp, err := lookup(ctx, token)
+		if err != nil {
+			return models.IntrospectionResponse{}, err
+		}
+		if resp.Active {
+			return resp, nil
+		}
+	}
+
+	return models.IntrospectionResponse{Active: false}, nil
+}
+
+func (s Service) introspectAccessToken(ctx context.Context, token string) (models.IntrospectionResponse, error) {
+	claims, err := auth.ParseTokenAnyAudience(token)
+	if err != nil {
+		return models.IntrospectionResponse{}, nil
+	}
+
+	userID, _ := claims["user_id"].(string)
+	pairID, _ := claims["token_pair_id"].(string)
+	if userID == "" {
+		return introspectClientToken(claims), nil
+	}
+	if pairID == "" {
+		return models.IntrospectionResponse{}, nil
+	}
+
+	revoked, err := s.IsRefreshTokenRevoked(ctx, userID, pairID)
+	if errors.Is(err, apperrors.ErrTokenIsNotFound) || revoked {
+		return models.IntrospectionResponse{}, nil
+	}
+	if err != nil {
+		return models.IntrospectionResponse{}, fmt.Errorf("check token pair status: %w", err)
+	}
+
+	resp := models.IntrospectionResponse{
+		Active:      true,
+		TokenType:   tokenTypeAccess,
+		Sub:         userID,
+		TokenPairID: pairID,
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		resp.Exp = exp.Unix()
+	}
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		resp.Iat = iat.Unix()
+	}
+	resp.ClientID, _ = claims["client_id"].(string)
+	resp.Scope, _ = claims["scope"].(string)
+	resp.Aud, _ = claims.GetAudience()
+
+	return resp, nil
+}
+
+// introspectClientToken describes a client_credentials token. Such tokens
+// have no token pair and cannot be revoked, they are active until exp.
+func introspectClientToken(claims jwt.MapClaims) models.IntrospectionResponse {
+	clientID, _ := claims["client_id"].(string)
+	if sub, _ := claims["sub"].(string); clientID == "" || sub != clientID {
+		return models.IntrospectionResponse{}
+	}
+
+	resp := models.IntrospectionResponse{
+		Active:    true,
+		TokenType: tokenTypeAccess,
+		Sub:       clientID,
+		ClientID:  clientID,
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		resp.Exp = exp.Unix()
+	}
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		resp.Iat = iat.Unix()
+	}
+	resp.Scope, _ = claims["scope"].(string)
+	resp.Aud, _ = claims.GetAudience()
+
+	return resp
+}
+
+func (s Service) introspectRefreshToken(ctx context.Context, token string) (models.IntrospectionResponse, error) {
+	refresh, err := s.lookupRefreshToken(ctx, "", "", token)
+	if errors.Is(err, apperrors.ErrTokenIsNotFound) {
+		return models.IntrospectionResponse{}, nil
+	}
+	if err != nil {
+		return models.IntrospectionResponse{}, fmt.Errorf("find refresh token: %w", err)
+	}
+
+	if s.verifyRefreshToken(refresh, token) != nil {
+		return models.IntrospectionResponse{}, nil
+	}
+
+	if refresh.Revoked || !time.Now().Before(refresh.ExpiresAt) {
+		return models.IntrospectionResponse{}, nil
+	}
+
+	return models.IntrospectionResponse{
+		Active:      true,
+		TokenType:   tokenTypeRefresh,
+		Sub:         refresh.UserID,
+		Exp:         refresh.ExpiresAt.Unix(),
+		Iat:         refresh.CreatedAt.Unix(),
+		TokenPairID: refresh.TokenPairID,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/auth"
+	"auth-service/models"
+	"context"
+	"fmt"
+	"github.com/gookit/slog"
+	"time"
+)
+
+// allowUser takes a token from the rate limit bucket of a user. Refreshes
+// charge it only once the access token proved the user_id, so nobody can
+// drain the bucket of another user. Like the middleware it fails open.
+func (s Service) allowUser(ctx context.Context, userID string) error {
+	result, err := s.limiter.AllowUser(ctx, userID)
+	if err != nil {
+		slog.Error("rate limit by user failed", "user_id", userID, "err", err)
+		return nil
+	}
+	if !result.Allowed {
+		return &apperrors.RateLimitError{Limit: result.Limit, RetryAfter: result.RetryAfter, Reset: result.Reset}
+	}
+	return nil
+}
+
+// checkLockout rejects refreshes of a user locked out by recordRefreshFailure.
+// The lockout store failing must not stop every refresh, so it is only logged.
+func (s Service) checkLockout(ctx context.Context, userID string) error {
+	until, err := s.limiter.RefreshLockedUntil(ctx, userID)
+	if err != nil {
+		slog.Error("failed to check refresh lockout", "user_id", userID, "err", err)
+		return nil
+	}
+	if !until.IsZero() {
+		return &apperrors.LockoutError{Until: until}
+	}
+	return nil
+}
+
+// recordRefreshFailure counts a refresh token that did not match the access
+// token pair it was presented with.
+func (s Service) recordRefreshFailure(ctx context.Context, userID, ip, userAgent string) {
+	until, err := s.limiter.RecordRefreshFailure(ctx, userID)
+	if err != nil {
+		slog.Error("failed to record refresh failure", "user_id", userID, "err", err)
+		return
+	}
+	if until.IsZero() {
+		return
+	}
+
+	slog.Warn("repeated invalid refresh tokens, user is locked out", "user_id", userID, "until", until)
+	s.audit(ctx, models.AuditEvent{
+		UserID:    userID,
+		Type:      models.AuditRefreshLockout,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"locked_until": until.Format(time.RFC3339),
+		},
+	})
+}
+
+// checkMFALockout rejects an mfa_pending token that used up its attempts and
+// users locked out by recordMFAFailure.
+func (s Service) checkMFALockout(ctx context.Context, pending auth.MFAPending) error {
+	until, err := s.limiter.MFATokenLockedUntil(ctx, pending.ID)
+	if err != nil {
+		slog.Error("failed to check mfa token attempts", "user_id", pending.UserID, "err", err)
+	} else if !until.IsZero() {
+		return fmt.Errorf("mfa token attempts exhausted: %w", apperrors.ErrInvalidToken)
+	}
+
+	until, err = s.limiter.MFALockedUntil(ctx, pending.UserID)
+	if err != nil {
+		slog.Error("failed to check mfa lockout", "user_id", pending.UserID, "err", err)
+		return nil
+	}
+	if !until.IsZero() {
+		return &apperrors.LockoutError{Until: until}
+	}
+	return nil
+}
+
+// recordMFAFailure counts a wrong second factor code against both the
+// mfa_pending token it came with and its user.
+func (s Service) recordMFAFailure(ctx context.Context, pending auth.MFAPending, ip, userAgent string) {
+	if _, err := s.limiter.RecordMFATokenFailure(ctx, pending.ID); err != nil {
+		slog.Error("failed to record mfa token failure", "user_id", pending.UserID, "err", err)
+	}
+
+	until, err := s.limiter.RecordMFAFailure(ctx, pending.UserID)
+	if err != nil {
+		slog.Error("failed to record mfa failure", "user_id", pending.UserID, "err", err)
+		return
+	}
+	if until.IsZero() {
+		return
+	}
+
+	slog.Warn("repeated invalid mfa codes, user is locked out", "user_id", pending.UserID, "until", until)
+	s.audit(ctx, models.AuditEvent{
+		UserID:    pending.UserID,
+		Type:      models.AuditMFALockout,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"locked_until": until.Format(time.RFC3339),
+		},
+	})
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/auth"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gookit/slog"
+	"time"
+)
+
+func (s Service) EnrollTOTP(ctx context.Context, userID string) (models.TOTPEnrollResponse, error) {
+	user, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return models.TOTPEnrollResponse{}, fmt.Errorf("find user: %w", err)
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return models.TOTPEnrollResponse{}, err
+	}
+
+	if err = s.repo.SaveTOTPSecret(ctx, userID, secret); err != nil {
+		return models.TOTPEnrollResponse{}, fmt.Errorf("save totp secret: %w", err)
+	}
+
+	return models.TOTPEnrollResponse{
+		Secret: secret,
+		URI:    auth.TOTPURI(s.cfg.MFA.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables the enrolled secret once the user proves it works with a
+// first code and returns the recovery codes. They are shown only this once.
+func (s Service) ConfirmTOTP(ctx context.Context, userID, code, ip, userAgent string) (models.RecoveryCodesResponse, error) {
+	totp, err := s.repo.FindTOTP(ctx, userID)
+	if err != nil {
+		return models.RecoveryCodesResponse{}, fmt.Errorf("find totp: %w", err)
+	}
+	if totp.ConfirmedAt != nil {
+		return models.RecoveryCodesResponse{}, apperrors.ErrMFAAlreadyEnabled
+	}
+
+	step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return models.RecoveryCodesResponse{}, apperrors.ErrInvalidMFACode
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(s.cfg.MFA.RecoveryCodesCount)
+	if err != nil {
+		return models.RecoveryCodesResponse{}, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, recoveryCode := range codes {
+		hashes[i] = auth.HashRecoveryCode([]byte(s.cfg.MFA.RecoveryCodeKey), recoveryCode)
+	}
+
+	event := models.AuditEvent{
+		UserID:    userID,
+		Type:      models.AuditMFAEnabled,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]interface{}{"method": "totp"},
+	}
+	if err = s.repo.ConfirmTOTP(ctx, userID, step, hashes, event); err != nil {
+		return models.RecoveryCodesResponse{}, fmt.Errorf("confirm totp: %w", err)
+	}
+
+	slog.Info("totp enabled", "user_id", userID)
+
+	return models.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// CompleteMFALogin finishes a login started by Login with either a TOTP code
+// or one of the recovery codes. Wrong codes count against the mfa_pending
+// token and the user, see recordMFAFailure.
+func (s Service) CompleteMFALogin(ctx context.Context, req models.MFALoginRequest, ip, userAgent string) (models.TokensResponse, error) {
+	pending, err := auth.ParseMFAToken(req.MFAToken)
+	if err != nil {
+		return models.TokensResponse{}, fmt.Errorf("parse mfa token: %w", apperrors.ErrInvalidToken)
+	}
+	userID, amr := pending.UserID, pending.AMR
+
+	if err = s.checkMFALockout(ctx, pending); err != nil {
+		return models.TokensResponse{}, err
+	}
+
+	totp, err := s.repo.FindTOTP(ctx, userID)
+	if errors.Is(err, apperrors.ErrMFANotEnrolled) || (err == nil && totp.ConfirmedAt == nil) {
+		return models.TokensResponse{}, apperrors.ErrMFANotEnrolled
+	}
+	if err != nil {
+		return models.TokensResponse{}, fmt.Errorf("find totp: %w", err)
+	}
+
+	switch {
+	case req.Code != "":
+		step, ok := auth.ValidateTOTP(totp.Secret, req.Code, time.Now())
+		if !ok {
+			s.recordMFAFailure(ctx, pending, ip, userAgent)
+			return models.TokensResponse{}, apperrors.ErrInvalidMFACode
+		}
+		if err = s.repo.UseTOTPStep(ctx, userID, step); err != nil {
+			return models.TokensResponse{}, fmt.Errorf("use totp step: %w", err)
+		}
+		amr = append(amr, models.AMROTP)
+	case req.RecoveryCode != "":
+		hash := auth.HashRecoveryCode([]byte(s.cfg.MFA.RecoveryCodeKey), req.RecoveryCode)
+		if err = s.repo.UseRecoveryCode(ctx, userID, hash); err != nil {
+			if errors.Is(err, apperrors.ErrInvalidMFACode) {
+				s.recordMFAFailure(ctx, pending, ip, userAgent)
+			}
+			return models.TokensResponse{}, fmt.Errorf("use recovery code: %w", err)
+		}
+		s.audit(ctx, models.AuditEvent{
+			UserID:    userID,
+			Type:      models.AuditRecoveryCodeUsed,
+			IP:        ip,
+			UserAgent: userAgent,
+		})
+	default:
+		return models.TokensResponse{}, apperrors.ErrInvalidMFACode
+	}
+
+	return s.GenerateTokens(ctx, models.Grant{
+		UserID:    userID,
+		IP:        ip,
+		UserAgent: userAgent,
+		AMR:       append(amr, models.AMRMFA),
+	})
+}
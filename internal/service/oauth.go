@@ -0,0 +1,435 @@
+package service
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/auth"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gookit/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	responseTypeCode = "code"
+	pkceMethodS256   = "S256"
+	promptNone       = "none"
+	promptLogin      = "login"
+)
+
+// ResolveRedirectURI returns the redirect URI the authorization response is
+// sent to. URIs are compared exactly; it may be omitted only when the client
+// has a single one registered. Errors from here must not be redirected.
+func (s Service) ResolveRedirectURI(ctx context.Context, clientID, redirectURI string) (string, error) {
+	client, err := s.repo.FindOAuthClient(ctx, clientID)
+	if errors.Is(err, apperrors.ErrClientNotFound) {
+		return "", apperrors.ErrInvalidClient
+	}
+	if err != nil {
+		return "", fmt.Errorf("find client: %w", err)
+	}
+
+	registered := client.RedirectURIs
+
+	if redirectURI == "" {
+		if len(registered) != 1 {
+			return "", apperrors.ErrInvalidRedirectURI
+		}
+		return registered[0], nil
+	}
+
+	if !slices.Contains(registered, redirectURI) {
+		return "", apperrors.ErrInvalidRedirectURI
+	}
+	return redirectURI, nil
+}
+
+// Authorize issues an authorization code for the session of the access token
+// the request was authenticated with.
+func (s Service) Authorize(ctx context.Context, userID, pairID string, req models.AuthorizeRequest) (string, error) {
+	if req.ResponseType != responseTypeCode {
+		return "", apperrors.NewOAuthError(apperrors.OAuthUnsupportedResponseType, "only response_type=code is supported")
+	}
+	if req.CodeChallenge == "" {
+		return "", apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "code_challenge is required")
+	}
+	if req.CodeChallengeMethod != pkceMethodS256 {
+		return "", apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "code_challenge_method must be S256")
+	}
+	if !auth.ValidPKCEValue(req.CodeChallenge) {
+		return "", apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "malformed code_challenge")
+	}
+
+	client, err := s.repo.FindOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		return "", fmt.Errorf("find client: %w", err)
+	}
+	if !client.AllowsGrant(models.GrantAuthorizationCode) {
+		return "", apperrors.NewOAuthError(apperrors.OAuthUnauthorizedClient, "client may not use the authorization_code grant")
+	}
+	scope, ok := resolveScope(req.Scope, client.Scopes)
+	if !ok {
+		return "", apperrors.NewOAuthError(apperrors.OAuthInvalidScope, "requested scope is not allowed for the client")
+	}
+	if err = checkOpenIDScope(scope); err != nil {
+		return "", err
+	}
+
+	session, err := s.repo.FindRefreshTokenByPairID(ctx, userID, pairID)
+	if err != nil {
+		return "", fmt.Errorf("find session: %w", err)
+	}
+	if err = s.checkAuthentication(session, req); err != nil {
+		return "", err
+	}
+
+	code, codeHash, err := auth.GenerateAuthorizationCode()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.repo.SaveAuthorizationCode(ctx, models.AuthorizationCode{
+		CodeHash:      codeHash,
+		ClientID:      req.ClientID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		CodeChallenge: req.CodeChallenge,
+		Scope:         scope,
+		Nonce:         req.Nonce,
+		AMR:           session.AMR,
+		AuthTime:      session.AuthTime,
+		ExpiresAt:     time.Now().Add(s.cfg.OAuth.CodeTTL),
+	})
+	if err != nil {
+		return "", fmt.Errorf("save authorization code: %w", err)
+	}
+
+	return code, nil
+}
+
+// checkAuthentication decides whether the login behind the session satisfies
+// the request. There is no login page to prompt with, so login_required sends
+// the client back to POST /login for a fresh session.
+func (s Service) checkAuthentication(session models.RefreshToken, req models.AuthorizeRequest) error {
+	// Sessions from before amr was recorded can not tell how the user logged in.
+	if len(session.AMR) == 0 {
+		return apperrors.NewOAuthError(apperrors.OAuthLoginRequired, "the session does not record how the user authenticated")
+	}
+
+	maxAge := time.Duration(-1)
+	if req.MaxAge != "" {
+		seconds, err := strconv.Atoi(req.MaxAge)
+		if err != nil || seconds < 0 {
+			return apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "max_age must be a non-negative integer")
+		}
+		maxAge = time.Duration(seconds) * time.Second
+	}
+
+	// max_age=0 asks for a fresh login the same way prompt=login does.
+	freshLogin := maxAge == 0
+	prompts := strings.Fields(req.Prompt)
+	for _, prompt := range prompts {
+		switch prompt {
+		case promptNone:
+			if len(prompts) > 1 {
+				return apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "prompt=none can not be combined with other values")
+			}
+		case promptLogin:
+			freshLogin = true
+		default:
+			return apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, fmt.Sprintf("unsupported prompt value %q", prompt))
+		}
+	}
+	if freshLogin && (maxAge <= 0 || maxAge > s.cfg.OAuth.PromptLoginMaxAge) {
+		maxAge = s.cfg.OAuth.PromptLoginMaxAge
+	}
+
+	if maxAge >= 0 && time.Since(session.AuthTime) > maxAge {
+		return apperrors.NewOAuthError(apperrors.OAuthLoginRequired, "the user has to log in again")
+	}
+	return nil
+}
+
+// ExchangeAuthorizationCode implements the authorization_code grant. The
+// code is bound to its client, redirect URI and PKCE challenge. Confidential
+// clients must authenticate in addition to PKCE.
+func (s Service) ExchangeAuthorizationCode(ctx context.Context, req models.TokenRequest, ip, userAgent string) (models.OAuthTokenResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" || req.ClientID == "" {
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "code, code_verifier and client_id are required")
+	}
+
+	client, err := s.repo.FindOAuthClient(ctx, req.ClientID)
+	if errors.Is(err, apperrors.ErrClientNotFound) {
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidClient, "unknown client")
+	}
+	if err != nil {
+		return models.OAuthTokenResponse{}, fmt.Errorf("find client: %w", err)
+	}
+	if client.Confidential() {
+		if client, err = s.authenticateClient(ctx, req.ClientID, req.ClientSecret); err != nil {
+			return models.OAuthTokenResponse{}, oauthClientError(err)
+		}
+	}
+	if !client.AllowsGrant(models.GrantAuthorizationCode) {
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthUnauthorizedClient, "client may not use the authorization_code grant")
+	}
+
+	familyID := uuid.New().String()
+
+	code, err := s.repo.ConsumeAuthorizationCode(ctx, auth.HashAuthorizationCode(req.Code), familyID)
+	if errors.Is(err, apperrors.ErrCodeReused) {
+		s.revokeCodeFamily(ctx, code, ip, userAgent)
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidGrant, "authorization code already used")
+	}
+	if errors.Is(err, apperrors.ErrCodeNotFound) {
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidGrant, "invalid or expired authorization code")
+	}
+	if err != nil {
+		return models.OAuthTokenResponse{}, fmt.Errorf("consume authorization code: %w", err)
+	}
+
+	switch {
+	case code.ClientID != req.ClientID:
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidGrant, "code was issued to another client")
+	case code.RedirectURI != req.RedirectURI:
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidGrant, "redirect_uri does not match the authorization request")
+	case !auth.VerifyPKCE(req.CodeVerifier, code.CodeChallenge):
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidGrant, "code_verifier does not match code_challenge")
+	}
+
+	grant := models.Grant{
+		UserID:    code.UserID,
+		IP:        ip,
+		UserAgent: userAgent,
+		AMR:       code.AMR,
+		AuthTime:  code.AuthTime,
+		ClientID:  code.ClientID,
+		Scope:     code.Scope,
+		Nonce:     code.Nonce,
+
+		Audience: s.accessAudience(client, code.Scope),
+
+		AccessTTL:  client.AccessTokenTTL,
+		RefreshTTL: client.RefreshTokenTTL,
+	}
+
+	tokens, err := s.issueTokens(ctx, grant, familyID, nil)
+	if err != nil {
+		return models.OAuthTokenResponse{}, fmt.Errorf("issue tokens: %w", err)
+	}
+
+	return models.OAuthTokenResponse{
+		AccessToken:  tokens.Access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTTL(client).Seconds()),
+		RefreshToken: tokens.Refresh,
+		IDToken:      tokens.IDToken,
+		Scope:        code.Scope,
+	}, nil
+}
+
+// ClientCredentialsGrant issues an access token to a confidential client
+// acting on its own behalf. There is no user session behind such a token, so
+// no refresh token is issued.
+func (s Service) ClientCredentialsGrant(ctx context.Context, req models.TokenRequest) (models.OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return models.OAuthTokenResponse{}, oauthClientError(err)
+	}
+	if !client.AllowsGrant(models.GrantClientCredentials) {
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthUnauthorizedClient, "client may not use the client_credentials grant")
+	}
+
+	scope, ok := resolveScope(req.Scope, client.Scopes)
+	if !ok {
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidScope, "requested scope is not allowed for the client")
+	}
+
+	ttl := s.accessTTL(client)
+	access, err := auth.GenerateClientAccessToken(client.ID, scope, s.accessAudience(client, scope), ttl)
+	if err != nil {
+		return models.OAuthTokenResponse{}, fmt.Errorf("generate client access token: %w", err)
+	}
+
+	slog.Info("client credentials token issued", "client_id", client.ID, "scope", scope)
+
+	return models.OAuthTokenResponse{
+		AccessToken: access,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(ttl.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// RefreshTokenGrant implements the refresh_token grant for the sessions of
+// OAuth clients. The refresh token is bound to the client it was issued to:
+// confidential clients authenticate, public ones present their client_id.
+// The scope of the session is kept, a presented scope is ignored.
+func (s Service) RefreshTokenGrant(ctx context.Context, req models.TokenRequest, ip, userAgent string) (models.OAuthTokenResponse, error) {
+	if req.RefreshToken == "" || req.ClientID == "" {
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidRequest, "refresh_token and client_id are required")
+	}
+
+	client, err := s.repo.FindOAuthClient(ctx, req.ClientID)
+	if errors.Is(err, apperrors.ErrClientNotFound) {
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidClient, "unknown client")
+	}
+	if err != nil {
+		return models.OAuthTokenResponse{}, fmt.Errorf("find client: %w", err)
+	}
+	if client.Confidential() {
+		if client, err = s.authenticateClient(ctx, req.ClientID, req.ClientSecret); err != nil {
+			return models.OAuthTokenResponse{}, oauthClientError(err)
+		}
+	}
+
+	invalidGrant := apperrors.NewOAuthError(apperrors.OAuthInvalidGrant, "invalid or expired refresh token")
+
+	// Only selector.verifier tokens were ever issued to OAuth clients.
+	selector, _, ok := auth.SplitRefreshToken(req.RefreshToken)
+	if !ok {
+		return models.OAuthTokenResponse{}, invalidGrant
+	}
+	token, err := s.repo.FindRefreshTokenBySelector(ctx, selector)
+	if errors.Is(err, apperrors.ErrTokenIsNotFound) {
+		return models.OAuthTokenResponse{}, invalidGrant
+	}
+	if err != nil {
+		return models.OAuthTokenResponse{}, fmt.Errorf("find refresh token: %w", err)
+	}
+	if token.ClientID != client.ID {
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidGrant, "refresh token was issued to another client")
+	}
+	if err = s.verifyRefreshToken(token, req.RefreshToken); err != nil {
+		return models.OAuthTokenResponse{}, invalidGrant
+	}
+
+	switch {
+	case token.Revoked && token.Rotated:
+		s.revokeReusedFamily(ctx, token, ip, userAgent)
+		return models.OAuthTokenResponse{}, apperrors.NewOAuthError(apperrors.OAuthInvalidGrant, "refresh token already used")
+	case token.Revoked, !time.Now().Before(token.ExpiresAt):
+		return models.OAuthTokenResponse{}, invalidGrant
+	}
+
+	tokens, err := s.rotateSession(ctx, token, ip, userAgent)
+	switch {
+	case errors.Is(err, apperrors.ErrUserDeauthorized), errors.Is(err, apperrors.ErrStepUpRequired),
+		errors.Is(err, apperrors.ErrInvalidToken):
+		return models.OAuthTokenResponse{}, invalidGrant
+	case err != nil:
+		return models.OAuthTokenResponse{}, fmt.Errorf("rotate session: %w", err)
+	}
+
+	return models.OAuthTokenResponse{
+		AccessToken:  tokens.Access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTTL(client).Seconds()),
+		RefreshToken: tokens.Refresh,
+		IDToken:      tokens.IDToken,
+		Scope:        token.Scope,
+	}, nil
+}
+
+func (s Service) accessTTL(client models.OAuthClient) time.Duration {
+	if client.AccessTokenTTL > 0 {
+		return client.AccessTokenTTL
+	}
+	return s.cfg.JWT.AccessTTL
+}
+
+// accessAudience returns the aud of access tokens issued to the client,
+// JWT_AUDIENCE when it has none registered. Tokens with the openid scope
+// must also be accepted by /userinfo.
+func (s Service) accessAudience(client models.OAuthClient, scope string) []string {
+	if len(client.Audiences) == 0 {
+		return nil
+	}
+	aud := slices.Clone(client.Audiences)
+	if hasScope(scope, scopeOpenID) && !slices.Contains(aud, s.cfg.JWT.Audience) {
+		aud = append(aud, s.cfg.JWT.Audience)
+	}
+	return aud
+}
+
+// oauthClientError maps a failed client authentication to invalid_client.
+func oauthClientError(err error) error {
+	if errors.Is(err, apperrors.ErrInvalidClient) {
+		return apperrors.NewOAuthError(apperrors.OAuthInvalidClient, "client authentication failed")
+	}
+	return err
+}
+
+// ImportLegacyClients registers the clients of the deprecated OAUTH_CLIENTS
+// that are missing from the registry, so the authorization code flow keeps
+// working for them. They become public authorization_code clients without
+// scopes, clients already in the registry are left as they are.
+func (s Service) ImportLegacyClients(ctx context.Context) error {
+	if len(s.cfg.OAuth.Clients) > 0 {
+		slog.Warn("OAUTH_CLIENTS is deprecated, register clients with \"auth-service clients create\"")
+	}
+
+	for id, redirectURIs := range s.cfg.OAuth.Clients {
+		_, err := s.repo.CreateOAuthClient(ctx, models.OAuthClient{
+			ID:           id,
+			RedirectURIs: redirectURIs,
+			GrantTypes:   []string{models.GrantAuthorizationCode},
+		})
+		if errors.Is(err, apperrors.ErrClientExists) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("import client %q: %w", id, err)
+		}
+		slog.Info("imported client of OAUTH_CLIENTS", "client_id", id)
+	}
+
+	return s.refreshClientTTL(ctx)
+}
+
+// resolveScope checks the space-delimited requested scope against the scopes
+// registered for the client. An empty request is granted all of them.
+func resolveScope(requested string, allowed []string) (string, bool) {
+	if requested == "" {
+		return strings.Join(allowed, " "), true
+	}
+	for _, scope := range strings.Fields(requested) {
+		if !slices.Contains(allowed, scope) {
+			return "", false
+		}
+	}
+	return strings.Join(strings.Fields(requested), " "), true
+}
+
+// revokeCodeFamily revokes the tokens issued for an authorization code that
+// is presented a second time, as RFC 6749 section 4.1.2 recommends.
+func (s Service) revokeCodeFamily(ctx context.Context, code models.AuthorizationCode, ip, userAgent string) {
+	var pairIDs []string
+	if code.FamilyID != "" {
+		var err error
+		if pairIDs, err = s.repo.RevokeRefreshTokenFamily(ctx, code.UserID, code.FamilyID, nil); err != nil {
+			slog.Error("failed to revoke tokens of a reused authorization code", "family_id", code.FamilyID, "err", err)
+		}
+		s.denyPairs(ctx, time.Now(), pairIDs...)
+	}
+
+	slog.Warn("authorization code reuse detected",
+		"user_id", code.UserID, "client_id", code.ClientID, "revoked", len(pairIDs))
+
+	s.audit(ctx, models.AuditEvent{
+		UserID:    code.UserID,
+		Type:      models.AuditAuthorizationCodeReuse,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"client_id":      code.ClientID,
+			"family_id":      code.FamilyID,
+			"revoked_tokens": len(pairIDs),
+		},
+	})
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/auth"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	scopeOpenID = "openid"
+	scopeEmail  = "email"
+)
+
+// OpenIDConfiguration returns the discovery document. OpenID Connect is only
+// offered while the active key is asymmetric, see auth.ErrSymmetricKey.
+func (s Service) OpenIDConfiguration() (models.OpenIDConfiguration, error) {
+	alg, err := auth.SigningAlgorithm()
+	if errors.Is(err, auth.ErrSymmetricKey) {
+		return models.OpenIDConfiguration{}, apperrors.ErrOIDCUnavailable
+	}
+	if err != nil {
+		return models.OpenIDConfiguration{}, fmt.Errorf("get signing algorithm: %w", err)
+	}
+
+	issuer := s.cfg.OIDC.Issuer
+	return models.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/authorize",
+		TokenEndpoint:                     issuer + "/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		IntrospectionEndpoint:             issuer + "/introspect",
+		RevocationEndpoint:                issuer + "/revoke",
+		ScopesSupported:                   []string{scopeOpenID, scopeEmail},
+		ResponseTypesSupported:            []string{responseTypeCode},
+		GrantTypesSupported:               []string{models.GrantAuthorizationCode, models.GrantClientCredentials, models.GrantRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{alg},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{pkceMethodS256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "azp", "exp", "iat", "auth_time", "nonce", "at_hash", "amr",
+			"email", "email_verified",
+		},
+	}, nil
+}
+
+// UserInfo returns the claims of the user released for the scope of the
+// access token. The route requires the openid scope.
+func (s Service) UserInfo(ctx context.Context, userID, scope string) (models.UserInfo, error) {
+	info := models.UserInfo{Sub: userID}
+	if !hasScope(scope, scopeEmail) {
+		return info, nil
+	}
+
+	user, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return models.UserInfo{}, fmt.Errorf("find user: %w", err)
+	}
+
+	// Addresses are not confirmed on registration.
+	verified := false
+	info.Email, info.EmailVerified = user.Email, &verified
+
+	return info, nil
+}
+
+// issueIDToken returns an ID token when the client was granted the openid
+// scope, and an empty string otherwise.
+func (s Service) issueIDToken(grant models.Grant, access string, ttl time.Duration) (string, error) {
+	if grant.ClientID == "" || !hasScope(grant.Scope, scopeOpenID) {
+		return "", nil
+	}
+
+	idToken, err := auth.GenerateIDToken(s.cfg.OIDC.Issuer, grant, access, ttl)
+	if errors.Is(err, auth.ErrSymmetricKey) {
+		return "", apperrors.ErrOIDCUnavailable
+	}
+	return idToken, err
+}
+
+// checkOpenIDScope refuses the openid scope while ID tokens can not be signed.
+func checkOpenIDScope(scope string) error {
+	if !hasScope(scope, scopeOpenID) {
+		return nil
+	}
+	if _, err := auth.SigningAlgorithm(); errors.Is(err, auth.ErrSymmetricKey) {
+		return apperrors.NewOAuthError(apperrors.OAuthInvalidScope, "openid requires an asymmetric signing key")
+	} else if err != nil {
+		return fmt.Errorf("get signing algorithm: %w", err)
+	}
+	return nil
+}
+
+func hasScope(scope, name string) bool {
+	return slices.Contains(strings.Fields(scope), name)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"auth-service/internal/utils"
+	"auth-service/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gookit/slog"
+	"math/rand/v2"
+	"time"
+)
+
+const maxListedOutboxEvents = 100
+
+// webhookEvent prepares an outbox event for WEBHOOK_URL, its type is added to
+// the payload as "event". Nothing is queued when no receiver is configured.
+func (s Service) webhookEvent(eventType string, payload map[string]interface{}) []models.OutboxEvent {
+	if s.cfg.Webhook.URL == "" {
+		return nil
+	}
+	payload["event"] = eventType
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("failed to encode webhook payload", "type", eventType, "err", err)
+		return nil
+	}
+
+	return []models.OutboxEvent{{Type: eventType, Payload: body}}
+}
+
+// RunOutboxDispatcher delivers queued webhook events until ctx is cancelled.
+// Every instance may run it: events are leased, so each one is sent by a
+// single instance at a time.
+func (s Service) RunOutboxDispatcher(ctx context.Context) {
+	if s.cfg.Webhook.URL == "" {
+		slog.Info("WEBHOOK_URL is not set, outbox dispatcher is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.Webhook.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// A full batch means more events are probably due.
+		if n := s.dispatchOutbox(ctx); n > 0 && n == s.cfg.Webhook.BatchSize && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchOutbox delivers one batch of due events and returns its size.
+func (s Service) dispatchOutbox(ctx context.Context) int {
+	// Deliveries are sequential, so the lease covers the whole batch.
+	lease := s.cfg.Webhook.Timeout * time.Duration(s.cfg.Webhook.BatchSize+1)
+
+	events, err := s.repo.ClaimOutboxEvents(ctx, s.cfg.Webhook.BatchSize, lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("failed to claim outbox events", "err", err)
+		}
+		return 0
+	}
+
+	for _, event := range events {
+		s.deliver(ctx, event)
+	}
+
+	return len(events)
+}
+
+func (s Service) deliver(ctx context.Context, event models.OutboxEvent) {
+	sendCtx, cancel := context.WithTimeout(ctx, s.cfg.Webhook.Timeout)
+	sendErr := utils.SendWebhook(sendCtx, s.cfg.Webhook.URL, event, s.webhookSecrets())
+	cancel()
+
+	var err error
+	switch {
+	case sendErr == nil:
+		err = s.repo.MarkOutboxEventDelivered(ctx, event.ID)
+	case event.Attempts >= s.cfg.Webhook.MaxAttempts:
+		slog.Error("webhook delivery failed, event is dead-lettered",
+			"id", event.ID, "type", event.Type, "attempts", event.Attempts, "err", sendErr)
+		err = s.repo.DeadLetterOutboxEvent(ctx, event.ID, sendErr.Error())
+	default:
+		retryAt := time.Now().Add(s.retryBackoff(event.Attempts))
+		slog.Warn("webhook delivery failed",
+			"id", event.ID, "type", event.Type, "attempts", event.Attempts, "retry_at", retryAt, "err", sendErr)
+		err = s.repo.RetryOutboxEvent(ctx, event.ID, retryAt, sendErr.Error())
+	}
+	if err != nil {
+		slog.Error("failed to update outbox event", "id", event.ID, "err", err)
+	}
+}
+
+func (s Service) webhookSecrets() [][]byte {
+	secrets := make([][]byte, 0, len(s.cfg.Webhook.Secrets))
+	for _, secret := range s.cfg.Webhook.Secrets {
+		secrets = append(secrets, []byte(secret))
+	}
+	return secrets
+}
+
+// retryBackoff doubles the delay with every attempt starting from
+// WEBHOOK_RETRY_MIN, caps it at WEBHOOK_RETRY_MAX and picks a random point in
+// its upper half, so that events failed together do not retry together.
+func (s Service) retryBackoff(attempts int) time.Duration {
+	delay := s.cfg.Webhook.RetryMin
+	for i := 1; i < attempts && delay < s.cfg.Webhook.RetryMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, s.cfg.Webhook.RetryMax)
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half+1)
+}
+
+func (s Service) ListOutboxEvents(ctx context.Context, status string, limit int) (models.OutboxEventsResponse, error) {
+	if limit <= 0 || limit > maxListedOutboxEvents {
+		limit = maxListedOutboxEvents
+	}
+
+	events, err := s.repo.ListOutboxEvents(ctx, status, limit)
+	if err != nil {
+		return models.OutboxEventsResponse{}, fmt.Errorf("list outbox events: %w", err)
+	}
+	if events == nil {
+		events = []models.OutboxEvent{}
+	}
+
+	return models.OutboxEventsResponse{Events: events}, nil
+}
+
+func (s Service) ReplayOutboxEvent(ctx context.Context, actorID string, id int64, ip, userAgent string) error {
+	event := outboxReplayEvent(actorID, ip, userAgent)
+	event.Details["id"] = id
+
+	if err := s.repo.ReplayOutboxEvent(ctx, id, event); err != nil {
+		return err
+	}
+
+	slog.Info("outbox event replayed", "id", id, "actor_id", actorID)
+
+	return nil
+}
+
+func (s Service) ReplayDeadOutboxEvents(ctx context.Context, actorID, ip, userAgent string) (models.OutboxReplayResponse, error) {
+	replayed, err := s.repo.ReplayDeadOutboxEvents(ctx, outboxReplayEvent(actorID, ip, userAgent))
+	if err != nil {
+		return models.OutboxReplayResponse{}, err
+	}
+
+	slog.Info("dead outbox events replayed", "replayed", replayed, "actor_id", actorID)
+
+	return models.OutboxReplayResponse{Replayed: replayed}, nil
+}
+
+func outboxReplayEvent(actorID, ip, userAgent string) models.AuditEvent {
+	return models.AuditEvent{
+		UserID:    actorID,
+		Type:      models.AuditOutboxReplayed,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]interface{}{},
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/models"
+	"context"
+	"fmt"
+	"github.com/gookit/slog"
+	"regexp"
+	"slices"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,63}$`)
+
+func (s Service) ListRoles(ctx context.Context) (models.RolesResponse, error) {
+	roles, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		return models.RolesResponse{}, fmt.Errorf("list roles: %w", err)
+	}
+	if roles == nil {
+		roles = []models.Role{}
+	}
+
+	return models.RolesResponse{Roles: roles}, nil
+}
+
+func (s Service) GetRole(ctx context.Context, name string) (models.Role, error) {
+	role, err := s.repo.FindRole(ctx, name)
+	if err != nil {
+		return models.Role{}, fmt.Errorf("find role: %w", err)
+	}
+	return role, nil
+}
+
+func (s Service) CreateRole(ctx context.Context, actorID string, req models.RoleRequest, ip, userAgent string) (models.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return models.Role{}, apperrors.ErrInvalidRoleName
+	}
+
+	role := models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: normalizePermissions(req.Permissions),
+	}
+
+	role, err := s.repo.CreateRole(ctx, role, roleEvent(models.AuditRoleCreated, actorID, role, ip, userAgent))
+	if err != nil {
+		return models.Role{}, fmt.Errorf("create role: %w", err)
+	}
+
+	slog.Info("role created", "role", role.Name, "permissions", role.Permissions, "actor_id", actorID)
+
+	return role, nil
+}
+
+// UpdateRole replaces the description and the permissions of the role. The
+// name can not be changed: it is embedded in issued access tokens.
+func (s Service) UpdateRole(ctx context.Context, actorID, name string, req models.RoleRequest, ip, userAgent string) (models.Role, error) {
+	if req.Name != "" && req.Name != name {
+		return models.Role{}, apperrors.ErrInvalidRoleName
+	}
+
+	role := models.Role{
+		Name:        name,
+		Description: req.Description,
+		Permissions: normalizePermissions(req.Permissions),
+	}
+
+	role, err := s.repo.UpdateRole(ctx, role, roleEvent(models.AuditRoleUpdated, actorID, role, ip, userAgent))
+	if err != nil {
+		return models.Role{}, fmt.Errorf("update role: %w", err)
+	}
+
+	slog.Info("role updated", "role", role.Name, "permissions", role.Permissions, "actor_id", actorID)
+
+	return role, nil
+}
+
+func (s Service) DeleteRole(ctx context.Context, actorID, name, ip, userAgent string) error {
+	role := models.Role{Name: name}
+	if err := s.repo.DeleteRole(ctx, name, roleEvent(models.AuditRoleDeleted, actorID, role, ip, userAgent)); err != nil {
+		return fmt.Errorf("delete role: %w", err)
+	}
+
+	slog.Info("role deleted", "role", name, "actor_id", actorID)
+
+	return nil
+}
+
+// AssignRole grants the role to the user. It is embedded in the access
+// tokens issued from the next login or refresh on.
+func (s Service) AssignRole(ctx context.Context, actorID, userID, role, ip, userAgent string) error {
+	event := models.AuditEvent{
+		UserID:    userID,
+		Type:      models.AuditRoleAssigned,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]interface{}{"role": role, "actor_id": actorID},
+	}
+	if err := s.repo.AssignRole(ctx, userID, role, event); err != nil {
+		return fmt.Errorf("assign role: %w", err)
+	}
+
+	slog.Info("role assigned", "role", role, "user_id", userID, "actor_id", actorID)
+
+	return nil
+}
+
+func (s Service) UnassignRole(ctx context.Context, actorID, userID, role, ip, userAgent string) error {
+	event := models.AuditEvent{
+		UserID:    userID,
+		Type:      models.AuditRoleUnassigned,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]interface{}{"role": role, "actor_id": actorID},
+	}
+	if err := s.repo.UnassignRole(ctx, userID, role, event); err != nil {
+		return fmt.Errorf("unassign role: %w", err)
+	}
+
+	slog.Info("role unassigned", "role", role, "user_id", userID, "actor_id", actorID)
+
+	return nil
+}
+
+// HasPermission reports whether any of the roles of an access token grants
+// the permission.
+func (s Service) HasPermission(ctx context.Context, roles []string, permission string) (bool, error) {
+	if len(roles) == 0 {
+		return false, nil
+	}
+
+	ok, err := s.repo.RolesHavePermission(ctx, roles, permission)
+	if err != nil {
+		return false, fmt.Errorf("check role permissions: %w", err)
+	}
+	return ok, nil
+}
+
+// userRoles returns the roles embedded in the access token of the grant.
+// A grant without AMR authenticated nobody and never gets roles.
+func (s Service) userRoles(ctx context.Context, grant models.Grant) ([]string, error) {
+	if len(grant.AMR) == 0 {
+		return nil, nil
+	}
+	return s.repo.ListUserRoles(ctx, grant.UserID)
+}
+
+func roleEvent(eventType, actorID string, role models.Role, ip, userAgent string) models.AuditEvent {
+	return models.AuditEvent{
+		UserID:    actorID,
+		Type:      eventType,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"role":        role.Name,
+			"permissions": role.Permissions,
+		},
+	}
+}
+
+func normalizePermissions(permissions []string) []string {
+	permissions = slices.Clone(permissions)
+	slices.Sort(permissions)
+	return slices.Compact(permissions)
+}
@@ -0,0 +1,398 @@
+package service
+
+import (
+	"auth-service/config"
+	"auth-service/internal/apperrors"
+	"auth-service/internal/auth"
+	"auth-service/internal/binding"
+	"auth-service/internal/ratelimit"
+	"auth-service/internal/repository"
+	"auth-service/internal/revocation"
+	"auth-service/models"
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAdminID   = "0b6c1a52-6c4f-4b8e-9a53-1f0d7e0c9a01"
+	testSupportID = "7d2f4c1e-3b5a-4f6d-8c9e-0a1b2c3d4e5f"
+)
+
+// memRepo is an in-memory RepositoryI covering roles and refresh tokens.
+// Calling any other method panics on the nil embedded interface.
+type memRepo struct {
+	repository.RepositoryI
+
+	mu          sync.Mutex
+	permissions []string
+	roles       map[string]models.Role
+	userRoles   map[string][]string
+	users       []string
+	tokens      []models.RefreshToken
+	events      []models.AuditEvent
+}
+
+func newMemRepo() *memRepo {
+	now := time.Now()
+	return &memRepo{
+		permissions: []string{models.PermissionRolesRead, models.PermissionRolesWrite},
+		roles: map[string]models.Role{
+			"admin": {
+				Name:        "admin",
+				Permissions: []string{models.PermissionRolesRead, models.PermissionRolesWrite},
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			},
+			"support": {
+				Name:        "support",
+				Permissions: []string{models.PermissionRolesRead},
+				CreatedAt:   now,
+				UpdatedAt:   now,
+			},
+		},
+		userRoles: map[string][]string{},
+		users:     []string{testAdminID, testSupportID},
+	}
+}
+
+func (m *memRepo) ListRoles(_ context.Context) ([]models.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var roles []models.Role
+	for _, role := range m.roles {
+		roles = append(roles, role)
+	}
+	slices.SortFunc(roles, func(a, b models.Role) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return roles, nil
+}
+
+func (m *memRepo) FindRole(_ context.Context, name string) (models.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	role, ok := m.roles[name]
+	if !ok {
+		return models.Role{}, apperrors.ErrRoleNotFound
+	}
+	return role, nil
+}
+
+func (m *memRepo) CreateRole(_ context.Context, role models.Role, event models.AuditEvent) (models.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.roles[role.Name]; ok {
+		return models.Role{}, apperrors.ErrRoleExists
+	}
+	if err := m.checkPermissions(role.Permissions); err != nil {
+		return models.Role{}, err
+	}
+
+	role.CreatedAt, role.UpdatedAt = time.Now(), time.Now()
+	m.roles[role.Name] = role
+	m.events = append(m.events, event)
+	return role, nil
+}
+
+func (m *memRepo) UpdateRole(_ context.Context, role models.Role, event models.AuditEvent) (models.Role, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.roles[role.Name]
+	if !ok {
+		return models.Role{}, apperrors.ErrRoleNotFound
+	}
+	if err := m.checkPermissions(role.Permissions); err != nil {
+		return models.Role{}, err
+	}
+
+	role.CreatedAt, role.UpdatedAt = existing.CreatedAt, time.Now()
+	m.roles[role.Name] = role
+	m.events = append(m.events, event)
+	return role, nil
+}
+
+func (m *memRepo) DeleteRole(_ context.Context, name string, event models.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.roles[name]; !ok {
+		return apperrors.ErrRoleNotFound
+	}
+	delete(m.roles, name)
+	for userID, roles := range m.userRoles {
+		m.userRoles[userID] = slices.DeleteFunc(roles, func(role string) bool { return role == name })
+	}
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memRepo) AssignRole(_ context.Context, userID, role string, event models.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.roles[role]; !ok {
+		return apperrors.ErrRoleNotFound
+	}
+	if !slices.Contains(m.users, userID) {
+		return apperrors.ErrUserNotFound
+	}
+	if !slices.Contains(m.userRoles[userID], role) {
+		m.userRoles[userID] = append(m.userRoles[userID], role)
+		slices.Sort(m.userRoles[userID])
+	}
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memRepo) UnassignRole(_ context.Context, userID, role string, event models.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := slices.Index(m.userRoles[userID], role)
+	if i < 0 {
+		return apperrors.ErrRoleNotAssigned
+	}
+	m.userRoles[userID] = slices.Delete(m.userRoles[userID], i, i+1)
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memRepo) ListUserRoles(_ context.Context, userID string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.userRoles[userID]), nil
+}
+
+func (m *memRepo) RolesHavePermission(_ context.Context, roles []string, permission string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range roles {
+		if role, ok := m.roles[name]; ok && slices.Contains(role.Permissions, permission) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memRepo) SaveRefreshToken(_ context.Context, token models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens = append(m.tokens, token)
+	return nil
+}
+
+func (m *memRepo) checkPermissions(permissions []string) error {
+	for _, permission := range permissions {
+		if !slices.Contains(m.permissions, permission) {
+			return apperrors.ErrUnknownPermission
+		}
+	}
+	return nil
+}
+
+func newTestService(t *testing.T, repo repository.RepositoryI) *Service {
+	t.Helper()
+
+	jwtCfg := config.JWT{
+		Algorithm:          "HS512",
+		Secret:             "test-secret",
+		AccessTTL:          15 * time.Minute,
+		RefreshTTL:         time.Hour,
+		SessionMaxLifetime: 24 * time.Hour,
+		RefreshHashKey:     "test-hash-key",
+		Audience:           "auth-service",
+	}
+	if err := auth.Init(jwtCfg); err != nil {
+		t.Fatalf("init keyring: %v", err)
+	}
+
+	return NewService(repo, config.Config{JWT: jwtCfg}, revocation.NewDenylist(), binding.New(),
+		ratelimit.NewLimiter(ratelimit.NewMemoryStore(), config.RateLimit{}, config.MFA{}))
+}
+
+func TestCreateRole(t *testing.T) {
+	repo := newMemRepo()
+	svc := newTestService(t, repo)
+	ctx := context.Background()
+
+	role, err := svc.CreateRole(ctx, testAdminID, models.RoleRequest{
+		Name:        "auditor",
+		Description: "Reads roles",
+		Permissions: []string{models.PermissionRolesRead, models.PermissionRolesRead},
+	}, "10.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+	if !slices.Equal(role.Permissions, []string{models.PermissionRolesRead}) {
+		t.Errorf("permissions = %v, want deduplicated [roles:read]", role.Permissions)
+	}
+	if got := repo.events[len(repo.events)-1]; got.Type != models.AuditRoleCreated || got.UserID != testAdminID {
+		t.Errorf("audit event = %+v, want role_created by the actor", got)
+	}
+
+	tests := []struct {
+		name string
+		req  models.RoleRequest
+		want error
+	}{
+		{"duplicate", models.RoleRequest{Name: "auditor"}, apperrors.ErrRoleExists},
+		{"uppercase name", models.RoleRequest{Name: "Auditor"}, apperrors.ErrInvalidRoleName},
+		{"empty name", models.RoleRequest{}, apperrors.ErrInvalidRoleName},
+		{"unknown permission", models.RoleRequest{Name: "ops", Permissions: []string{"servers:reboot"}}, apperrors.ErrUnknownPermission},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.CreateRole(ctx, testAdminID, tt.req, "", ""); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateRole(t *testing.T) {
+	repo := newMemRepo()
+	svc := newTestService(t, repo)
+	ctx := context.Background()
+
+	role, err := svc.UpdateRole(ctx, testAdminID, "support", models.RoleRequest{
+		Description: "Support staff",
+		Permissions: []string{models.PermissionRolesWrite, models.PermissionRolesRead},
+	}, "", "")
+	if err != nil {
+		t.Fatalf("update role: %v", err)
+	}
+	if !slices.Equal(role.Permissions, []string{models.PermissionRolesRead, models.PermissionRolesWrite}) {
+		t.Errorf("permissions = %v, want sorted [roles:read roles:write]", role.Permissions)
+	}
+
+	if _, err = svc.UpdateRole(ctx, testAdminID, "support", models.RoleRequest{Name: "helpdesk"}, "", ""); !errors.Is(err, apperrors.ErrInvalidRoleName) {
+		t.Errorf("rename: err = %v, want ErrInvalidRoleName", err)
+	}
+	if _, err = svc.UpdateRole(ctx, testAdminID, "missing", models.RoleRequest{}, "", ""); !errors.Is(err, apperrors.ErrRoleNotFound) {
+		t.Errorf("missing role: err = %v, want ErrRoleNotFound", err)
+	}
+}
+
+func TestAssignRoleGrantsPermissions(t *testing.T) {
+	repo := newMemRepo()
+	svc := newTestService(t, repo)
+	ctx := context.Background()
+
+	if err := svc.AssignRole(ctx, testAdminID, testSupportID, "support", "", ""); err != nil {
+		t.Fatalf("assign role: %v", err)
+	}
+	if err := svc.AssignRole(ctx, testAdminID, testSupportID, "missing", "", ""); !errors.Is(err, apperrors.ErrRoleNotFound) {
+		t.Errorf("missing role: err = %v, want ErrRoleNotFound", err)
+	}
+	if err := svc.AssignRole(ctx, testAdminID, "00000000-0000-0000-0000-000000000000", "support", "", ""); !errors.Is(err, apperrors.ErrUserNotFound) {
+		t.Errorf("missing user: err = %v, want ErrUserNotFound", err)
+	}
+
+	roles, _ := repo.ListUserRoles(ctx, testSupportID)
+
+	tests := []struct {
+		permission string
+		want       bool
+	}{
+		{models.PermissionRolesRead, true},
+		{models.PermissionRolesWrite, false},
+	}
+	for _, tt := range tests {
+		got, err := svc.HasPermission(ctx, roles, tt.permission)
+		if err != nil {
+			t.Fatalf("has permission: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("HasPermission(%v, %s) = %v, want %v", roles, tt.permission, got, tt.want)
+		}
+	}
+
+	if ok, _ := svc.HasPermission(ctx, nil, models.PermissionRolesRead); ok {
+		t.Error("a token without roles must have no permissions")
+	}
+
+	if err := svc.UnassignRole(ctx, testAdminID, testSupportID, "support", "", ""); err != nil {
+		t.Fatalf("unassign role: %v", err)
+	}
+	if err := svc.UnassignRole(ctx, testAdminID, testSupportID, "support", "", ""); !errors.Is(err, apperrors.ErrRoleNotAssigned) {
+		t.Errorf("second unassign: err = %v, want ErrRoleNotAssigned", err)
+	}
+}
+
+func TestDeleteRoleRemovesAssignments(t *testing.T) {
+	repo := newMemRepo()
+	svc := newTestService(t, repo)
+	ctx := context.Background()
+
+	if err := svc.AssignRole(ctx, testAdminID, testSupportID, "support", "", ""); err != nil {
+		t.Fatalf("assign role: %v", err)
+	}
+	if err := svc.DeleteRole(ctx, testAdminID, "support", "", ""); err != nil {
+		t.Fatalf("delete role: %v", err)
+	}
+
+	if roles, _ := repo.ListUserRoles(ctx, testSupportID); len(roles) != 0 {
+		t.Errorf("roles after delete = %v, want none", roles)
+	}
+	if _, err := svc.GetRole(ctx, "support"); !errors.Is(err, apperrors.ErrRoleNotFound) {
+		t.Errorf("get deleted role: err = %v, want ErrRoleNotFound", err)
+	}
+	if err := svc.DeleteRole(ctx, testAdminID, "support", "", ""); !errors.Is(err, apperrors.ErrRoleNotFound) {
+		t.Errorf("second delete: err = %v, want ErrRoleNotFound", err)
+	}
+}
+
+func TestAccessTokenCarriesRoles(t *testing.T) {
+	repo := newMemRepo()
+	svc := newTestService(t, repo)
+	ctx := context.Background()
+
+	if err := svc.AssignRole(ctx, testAdminID, testAdminID, "admin", "", ""); err != nil {
+		t.Fatalf("assign role: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		grant models.Grant
+		want  []string
+	}{
+		{
+			name:  "password login",
+			grant: models.Grant{UserID: testAdminID, AMR: []string{models.AMRPassword}},
+			want:  []string{"admin"},
+		},
+		{
+			name:  "grant without authentication",
+			grant: models.Grant{UserID: testAdminID},
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := svc.GenerateTokens(ctx, tt.grant)
+			if err != nil {
+				t.Fatalf("generate tokens: %v", err)
+			}
+
+			claims, err := auth.ParseAndValidateToken(tokens.Access)
+			if err != nil {
+				t.Fatalf("parse access token: %v", err)
+			}
+			if got := auth.ClaimStrings(claims, "roles"); !slices.Equal(got, tt.want) {
+				t.Errorf("roles claim = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
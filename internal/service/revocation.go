@@ -0,0 +1,129 @@
+package service
+
+import (
+	"auth-service/models"
+	"context"
+	"fmt"
+	"github.com/gookit/slog"
+	"time"
+)
+
+const (
+	minRevocationBackoff = 100 * time.Millisecond
+	maxRevocationBackoff = 30 * time.Second
+)
+
+func (s Service) IsTokenPairRevoked(pairID string) bool {
+	return s.denylist.IsRevoked(pairID)
+}
+
+// LoadRevocations fills the denylist with every pair revoked recently enough
+// for its access tokens to still be unexpired, given the access token
+// lifetime of the client of each pair.
+func (s Service) LoadRevocations(ctx context.Context) error {
+	pairs, err := s.repo.ListRevokedPairs(ctx, s.cfg.JWT.AccessTTL)
+	if err != nil {
+		return fmt.Errorf("list revoked pairs: %w", err)
+	}
+
+	entries := make(map[string]time.Time, len(pairs))
+	for _, pair := range pairs {
+		entries[pair.PairID] = pair.AccessExpiresAt
+	}
+	s.denylist.Replace(entries)
+
+	return nil
+}
+
+// RunRevocationSync keeps the denylist in sync with revocations made by other
+// instances. Every (re)connection starts with a full reload, because
+// notifications sent while the listener was down are lost.
+func (s Service) RunRevocationSync(ctx context.Context) {
+	backoff := minRevocationBackoff
+
+	for {
+		err := s.repo.ListenRevocations(ctx, func(ctx context.Context) error {
+			if err := s.LoadRevocations(ctx); err != nil {
+				return err
+			}
+			backoff = minRevocationBackoff
+			slog.Info("revocation listener connected", "revoked_pairs", s.denylist.Len())
+			return nil
+		}, func(event models.RevocationEvent) {
+			s.denyPairs(ctx, event.RevokedAt, event.PairIDs...)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+
+		slog.Error("revocation listener disconnected", "err", err, "retry_in", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRevocationBackoff)
+	}
+}
+
+func (s Service) revokePair(ctx context.Context, userID, pairID string) error {
+	if err := s.repo.RevokeRefreshTokenByPairID(ctx, userID, pairID); err != nil {
+		return err
+	}
+
+	s.denyPairs(ctx, time.Now(), pairID)
+	return nil
+}
+
+// denyPairs puts revoked pairs on the denylist until the last access token
+// issued for them has expired. The clients of the pairs are not known here,
+// so the longest access token lifetime of any client is assumed.
+func (s Service) denyPairs(ctx context.Context, revokedAt time.Time, pairIDs ...string) {
+	until := revokedAt.Add(s.maxAccessTTL(ctx))
+	for _, pairID := range pairIDs {
+		s.denylist.Add(pairID, until)
+	}
+}
+
+// maxAccessTTL returns the longest lifetime an access token may have. Keeping
+// a revoked pair denied for too long costs nothing but memory, so until the
+// clients have been read the refresh token lifetime is used as a bound.
+func (s Service) maxAccessTTL(ctx context.Context) time.Duration {
+	ttl := s.cfg.JWT.RefreshTTL
+	if cached := s.clientTTL.Load(); cached != nil {
+		ttl = *cached
+	} else if err := s.refreshClientTTL(ctx); err == nil {
+		ttl = *s.clientTTL.Load()
+	}
+	return max(ttl, s.cfg.JWT.AccessTTL)
+}
+
+// refreshClientTTL reloads the longest client access token lifetime used by
+// maxAccessTTL.
+func (s Service) refreshClientTTL(ctx context.Context) error {
+	ttl, err := s.repo.MaxOAuthClientAccessTTL(ctx)
+	if err != nil {
+		slog.Error("failed to find the longest client access token ttl", "err", err)
+		return err
+	}
+	s.clientTTL.Store(&ttl)
+	return nil
+}
+
+// RunClientTTLRefresh periodically reloads the longest client access token
+// lifetime until ctx is cancelled. Clients are registered by the admin
+// command in another process, so the change is picked up within interval.
+func (s Service) RunClientTTLRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = s.refreshClientTTL(ctx)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/auth"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gookit/slog"
+)
+
+// Revoke implements RFC 7009: unknown, malformed and already revoked tokens
+// are not reported as errors, only storage failures are.
+func (s Service) Revoke(ctx context.Context, token, tokenTypeHint string) error {
+	userID, pairID, ok, err := s.findTokenPair(ctx, token, tokenTypeHint)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	err = s.revokePair(ctx, userID, pairID)
+	if errors.Is(err, apperrors.ErrAlreadyLoggedOut) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("revoke refresh token: %w", err)
+	}
+
+	slog.Info("token pair revoked", "user_id", userID, "token_pair_id", pairID)
+
+	return nil
+}
+
+func (s Service) findTokenPair(ctx context.Context, token, tokenTypeHint string) (string, string, bool, error) {
+	if tokenTypeHint == tokenTypeRefresh {
+		userID, pairID, ok, err := s.pairFromRefreshToken(ctx, token)
+		if err != nil || ok {
+			return userID, pairID, ok, err
+		}
+		userID, pairID, ok = pairFromAccessToken(token)
+		return userID, pairID, ok, nil
+	}
+
+	if userID, pairID, ok := pairFromAccessToken(token); ok {
+		return userID, pairID, true, nil
+	}
+	return s.pairFromRefreshToken(ctx, token)
+}
+
+func pairFromAccessToken(token string) (string, string, bool) {
+	claims, err := auth.ParseTokenIgnoringExpiry(token)
+	if err != nil {
+		return "", "", false
+	}
+
+	userID, _ := claims["user_id"].(string)
+	pairID, _ := claims["token_pair_id"].(string)
+	return userID, pairID, userID != "" && pairID != ""
+}
+
+func (s Service) pairFromRefreshToken(ctx context.Context, token string) (string, string, bool, error) {
+	refresh, err := s.lookupRefreshToken(ctx, "", "", token)
+	if errors.Is(err, apperrors.ErrTokenIsNotFound) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, fmt.Errorf("find refresh token: %w", err)
+	}
+
+	if s.verifyRefreshToken(refresh, token) != nil {
+		return "", "", false, nil
+	}
+
+	return refresh.UserID, refresh.TokenPairID, true, nil
+}
@@ -1,25 +1,77 @@
 package service
 
 import (
+	"auth-service/config"
+	"auth-service/internal/binding"
+	"auth-service/internal/ratelimit"
 	"auth-service/internal/repository"
+	"auth-service/internal/revocation"
 	"auth-service/models"
 	"context"
+	"sync/atomic"
+	"time"
 )
 
 type ServiceI interface {
-	GenerateTokens(ctx context.Context, userID, ip, userAgent string) (models.TokensResponse, error)
 	RefreshTokens(ctx context.Context, userID, access, refresh, userAgent, ip string) (models.TokensResponse, error)
 	Logout(ctx context.Context, userID, accessToken string) error
 	ParseAccessTokenClaims(token string) (map[string]interface{}, error)
 	IsRefreshTokenRevoked(ctx context.Context, userID, pairID string) (bool, error)
+	IsTokenPairRevoked(pairID string) bool
+	PublicKeys() (models.JWKSet, error)
+	RevokeSession(ctx context.Context, userID, pairID, ip, userAgent string) error
+	LogoutAll(ctx context.Context, userID, currentPairID string, keepCurrent bool, ip, userAgent string) (int, error)
+	ListSessions(ctx context.Context, userID, currentPairID, cursor string, limit int) (models.SessionsResponse, error)
+	AuthenticateClient(ctx context.Context, clientID, clientSecret string) error
+	Introspect(ctx context.Context, token, tokenTypeHint string) (models.IntrospectionResponse, error)
+	Revoke(ctx context.Context, token, tokenTypeHint string) error
+	Register(ctx context.Context, email, password string) (models.User, error)
+	Login(ctx context.Context, email, password, ip, userAgent string) (models.LoginResponse, error)
+	CompleteMFALogin(ctx context.Context, req models.MFALoginRequest, ip, userAgent string) (models.TokensResponse, error)
+	EnrollTOTP(ctx context.Context, userID string) (models.TOTPEnrollResponse, error)
+	ConfirmTOTP(ctx context.Context, userID, code, ip, userAgent string) (models.RecoveryCodesResponse, error)
+	BeginWebAuthnRegistration(ctx context.Context, userID string) (models.WebAuthnRegisterBeginResponse, error)
+	FinishWebAuthnRegistration(ctx context.Context, userID string, req models.WebAuthnRegisterFinishRequest, ip, userAgent string) (models.WebAuthnCredentialResponse, error)
+	BeginWebAuthnLogin(ctx context.Context, email string) (models.WebAuthnLoginBeginResponse, error)
+	FinishWebAuthnLogin(ctx context.Context, req models.WebAuthnLoginFinishRequest, ip, userAgent string) (models.TokensResponse, error)
+	ResolveRedirectURI(ctx context.Context, clientID, redirectURI string) (string, error)
+	Authorize(ctx context.Context, userID, pairID string, req models.AuthorizeRequest) (string, error)
+	ExchangeAuthorizationCode(ctx context.Context, req models.TokenRequest, ip, userAgent string) (models.OAuthTokenResponse, error)
+	ClientCredentialsGrant(ctx context.Context, req models.TokenRequest) (models.OAuthTokenResponse, error)
+	RefreshTokenGrant(ctx context.Context, req models.TokenRequest, ip, userAgent string) (models.OAuthTokenResponse, error)
+	OpenIDConfiguration() (models.OpenIDConfiguration, error)
+	UserInfo(ctx context.Context, userID, scope string) (models.UserInfo, error)
+	ListRoles(ctx context.Context) (models.RolesResponse, error)
+	GetRole(ctx context.Context, name string) (models.Role, error)
+	CreateRole(ctx context.Context, actorID string, req models.RoleRequest, ip, userAgent string) (models.Role, error)
+	UpdateRole(ctx context.Context, actorID, name string, req models.RoleRequest, ip, userAgent string) (models.Role, error)
+	DeleteRole(ctx context.Context, actorID, name, ip, userAgent string) error
+	AssignRole(ctx context.Context, actorID, userID, role, ip, userAgent string) error
+	UnassignRole(ctx context.Context, actorID, userID, role, ip, userAgent string) error
+	HasPermission(ctx context.Context, roles []string, permission string) (bool, error)
+	ListOutboxEvents(ctx context.Context, status string, limit int) (models.OutboxEventsResponse, error)
+	ReplayOutboxEvent(ctx context.Context, actorID string, id int64, ip, userAgent string) error
+	ReplayDeadOutboxEvents(ctx context.Context, actorID, ip, userAgent string) (models.OutboxReplayResponse, error)
 }
 
 type Service struct {
-	repo repository.RepositoryI
+	repo     repository.RepositoryI
+	cfg      config.Config
+	denylist *revocation.Denylist
+	binding  *binding.Policy
+	limiter  *ratelimit.Limiter
+	// clientTTL caches the longest client access token lifetime, see
+	// RunClientTTLRefresh.
+	clientTTL *atomic.Pointer[time.Duration]
 }
 
-func NewService(repo repository.RepositoryI) *Service {
+func NewService(repo repository.RepositoryI, cfg config.Config, denylist *revocation.Denylist, binding *binding.Policy, limiter *ratelimit.Limiter) *Service {
 	return &Service{
-		repo: repo,
+		repo:      repo,
+		cfg:       cfg,
+		denylist:  denylist,
+		binding:   binding,
+		limiter:   limiter,
+		clientTTL: new(atomic.Pointer[time.Duration]),
 	}
 }
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/useragent"
+	"auth-service/models"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/gookit/slog"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultSessionsLimit = 20
+	maxSessionsLimit     = 100
+)
+
+func (s Service) ListSessions(ctx context.Context, userID, currentPairID, cursor string, limit int) (models.SessionsResponse, error) {
+	if limit <= 0 {
+		limit = defaultSessionsLimit
+	}
+	if limit > maxSessionsLimit {
+		limit = maxSessionsLimit
+	}
+
+	beforeID, err := decodeSessionsCursor(cursor)
+	if err != nil {
+		return models.SessionsResponse{}, err
+	}
+
+	tokens, err := s.repo.ListActiveRefreshTokens(ctx, userID, beforeID, limit+1)
+	if err != nil {
+		return models.SessionsResponse{}, fmt.Errorf("list active refresh tokens: %w", err)
+	}
+
+	resp := models.SessionsResponse{Sessions: make([]models.Session, 0, limit)}
+	if len(tokens) > limit {
+		tokens = tokens[:limit]
+		resp.NextCursor = encodeSessionsCursor(tokens[limit-1].ID)
+	}
+
+	for _, token := range tokens {
+		ua := useragent.Parse(token.UserAgent)
+		resp.Sessions = append(resp.Sessions, models.Session{
+			PairID:     token.TokenPairID,
+			Device:     ua.String(),
+			Browser:    ua.Browser,
+			OS:         ua.OS,
+			DeviceType: ua.Device,
+			IP:         token.IP,
+			CreatedAt:  token.AuthTime,
+			LastUsedAt: token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    token.TokenPairID == currentPairID,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s Service) RevokeSession(ctx context.Context, userID, pairID, ip, userAgent string) error {
+	pairIDs, err := s.repo.RevokeSession(ctx, userID, pairID, models.AuditEvent{
+		UserID:    userID,
+		Type:      models.AuditSessionRevoked,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]interface{}{"token_pair_id": pairID},
+	})
+	if err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+	s.denyPairs(ctx, time.Now(), pairIDs...)
+
+	slog.Info("session revoked", "user_id", userID, "token_pair_id", pairID, "revoked", len(pairIDs))
+
+	return nil
+}
+
+func (s Service) LogoutAll(ctx context.Context, userID, currentPairID string, keepCurrent bool, ip, userAgent string) (int, error) {
+	exceptPairID := ""
+	if keepCurrent {
+		exceptPairID = currentPairID
+	}
+
+	pairIDs, err := s.repo.RevokeAllSessions(ctx, userID, exceptPairID, models.AuditEvent{
+		UserID:    userID,
+		Type:      models.AuditLogoutAll,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details:   map[string]interface{}{"keep_current": keepCurrent, "token_pair_id": currentPairID},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("revoke all sessions: %w", err)
+	}
+	s.denyPairs(ctx, time.Now(), pairIDs...)
+
+	slog.Info("user logged out everywhere", "user_id", userID, "keep_current", keepCurrent, "revoked", len(pairIDs))
+
+	return len(pairIDs), nil
+}
+
+func encodeSessionsCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeSessionsCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return math.MaxInt32, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, apperrors.ErrInvalidCursor
+	}
+
+	id, err := strconv.Atoi(string(raw))
+	if err != nil || id <= 0 {
+		return 0, apperrors.ErrInvalidCursor
+	}
+
+	return id, nil
+}
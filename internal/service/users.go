@@ -0,0 +1,146 @@
+package service
+
+import (
+	"auth-service/config"
+	"auth-service/internal/apperrors"
+	"auth-service/internal/auth"
+	"auth-service/models"
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// dummyPassword holds a hash that is verified when the email is unknown, so a
+// login attempt takes the same time whether the account exists or not.
+var dummyPassword struct {
+	once sync.Once
+	hash string
+}
+
+func (s Service) Register(ctx context.Context, email, password string) (models.User, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if err = validatePassword(s.cfg.Password, password); err != nil {
+		return models.User{}, err
+	}
+
+	hash, err := auth.HashPassword(password, s.cfg.Password.Argon2)
+	if err != nil {
+		return models.User{}, fmt.Errorf("hash password: %w", err)
+	}
+
+	user, err := s.repo.CreateUser(ctx, models.User{Email: email, PasswordHash: hash})
+	if err != nil {
+		return models.User{}, fmt.Errorf("create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// Login checks the password. When the account has a confirmed second factor
+// no token pair is issued, the caller gets an mfa_pending token instead.
+func (s Service) Login(ctx context.Context, email, password, ip, userAgent string) (models.LoginResponse, error) {
+	user, err := s.repo.FindUserByEmail(ctx, strings.TrimSpace(email))
+	if errors.Is(err, apperrors.ErrUserNotFound) {
+		_, _ = auth.VerifyPassword(password, s.dummyPasswordHash())
+		return models.LoginResponse{}, apperrors.ErrBadCredentials
+	}
+	if err != nil {
+		return models.LoginResponse{}, fmt.Errorf("find user: %w", err)
+	}
+
+	ok, err := auth.VerifyPassword(password, user.PasswordHash)
+	if err != nil {
+		return models.LoginResponse{}, fmt.Errorf("verify password: %w", err)
+	}
+	if !ok {
+		return models.LoginResponse{}, apperrors.ErrBadCredentials
+	}
+
+	amr := []string{models.AMRPassword}
+
+	totp, err := s.repo.FindTOTP(ctx, user.ID)
+	if err != nil && !errors.Is(err, apperrors.ErrMFANotEnrolled) {
+		return models.LoginResponse{}, fmt.Errorf("find totp: %w", err)
+	}
+	if err == nil && totp.ConfirmedAt != nil {
+		mfaToken, err := auth.GenerateMFAToken(user.ID, amr, s.cfg.MFA.PendingTTL)
+		if err != nil {
+			return models.LoginResponse{}, fmt.Errorf("generate mfa token: %w", err)
+		}
+		return models.LoginResponse{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	tokens, err := s.GenerateTokens(ctx, models.Grant{
+		UserID:    user.ID,
+		IP:        ip,
+		UserAgent: userAgent,
+		AMR:       amr,
+	})
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	return models.LoginResponse{Access: tokens.Access, Refresh: tokens.Refresh}, nil
+}
+
+func (s Service) dummyPasswordHash() string {
+	dummyPassword.once.Do(func() {
+		dummyPassword.hash, _ = auth.HashPassword("dummy password", s.cfg.Password.Argon2)
+	})
+	return dummyPassword.hash
+}
+
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", apperrors.ErrInvalidEmail
+	}
+	return email, nil
+}
+
+func validatePassword(policy config.Password, password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		return fmt.Errorf("password shorter than %d characters: %w", policy.MinLength, apperrors.ErrWeakPassword)
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		return fmt.Errorf("password longer than %d characters: %w", policy.MaxLength, apperrors.ErrWeakPassword)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	switch {
+	case policy.RequireUpper && !upper:
+		return fmt.Errorf("password has no uppercase letter: %w", apperrors.ErrWeakPassword)
+	case policy.RequireLower && !lower:
+		return fmt.Errorf("password has no lowercase letter: %w", apperrors.ErrWeakPassword)
+	case policy.RequireDigit && !digit:
+		return fmt.Errorf("password has no digit: %w", apperrors.ErrWeakPassword)
+	case policy.RequireSymbol && !symbol:
+		return fmt.Errorf("password has no symbol: %w", apperrors.ErrWeakPassword)
+	}
+
+	return nil
+}
@@ -5,16 +5,15 @@ import (
 	"auth-service/internal/auth"
 	"auth-service/models"
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gookit/slog"
-	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 func (s Service) validateAccessToken(access, userID string) (string, error) {
-	accessClaims, err := auth.ParseAndValidateToken(access)
+	accessClaims, err := auth.ParseTokenForRefresh(access, s.cfg.JWT.RefreshAccessMaxAge)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return "", fmt.Errorf("access token expired: %w", apperrors.ErrTokenExpired)
@@ -36,33 +35,87 @@ func (s Service) validateAccessToken(access, userID string) (string, error) {
 }
 
 func (s Service) validateRefreshToken(ctx context.Context, userID, pairID, refresh string) (models.RefreshToken, error) {
-	token, err := s.repo.FindRefreshTokenByPairID(ctx, userID, pairID)
+	token, err := s.lookupRefreshToken(ctx, userID, pairID, refresh)
 	if err != nil {
 		return models.RefreshToken{},
 			fmt.Errorf("refresh token not found: %w", apperrors.ErrTokenIsNotFound)
 	}
 
+	if token.UserID != userID {
+		return models.RefreshToken{},
+			fmt.Errorf("refresh token belongs to another user: %w", apperrors.ErrInvalidToken)
+	}
+
 	if token.Revoked {
+		if token.Rotated && s.verifyRefreshToken(token, refresh) == nil {
+			return token, fmt.Errorf("rotated refresh token presented again: %w", apperrors.ErrTokenReused)
+		}
 		return models.RefreshToken{},
 			fmt.Errorf("refresh token revoked: %w", apperrors.ErrTokenRevoked)
 	}
 
-	decRefresh, err := base64.URLEncoding.DecodeString(refresh)
-	if err != nil {
-		if revokeErr := s.repo.RevokeRefreshTokenByPairID(ctx, userID, pairID); revokeErr != nil {
-			slog.Error("failed to revoke refresh token on decode error")
+	if token.TokenPairID != pairID {
+		if revokeErr := s.revokePair(ctx, userID, pairID); revokeErr != nil {
+			slog.Error("failed to revoke refresh token on pair mismatch", "err", revokeErr)
 		}
 		return models.RefreshToken{},
-			fmt.Errorf("invalid refresh token encoding: %w", apperrors.ErrInvalidToken)
+			fmt.Errorf("refresh token does not belong to the access token pair: %w", apperrors.ErrInvalidToken)
 	}
 
-	if err = bcrypt.CompareHashAndPassword([]byte(token.TokenHash), decRefresh); err != nil {
-		if revokeErr := s.repo.RevokeRefreshTokenByPairID(ctx, userID, pairID); revokeErr != nil {
-			slog.Error("failed to revoke refresh token on hash mismatch")
-		}
+	if !time.Now().Before(token.ExpiresAt) {
 		return models.RefreshToken{},
-			fmt.Errorf("refresh token hash mismatch: %w", apperrors.ErrInvalidToken)
+			fmt.Errorf("refresh token expired at %s: %w", token.ExpiresAt.Format(time.RFC3339), apperrors.ErrRefreshExpired)
+	}
+
+	if err = s.verifyRefreshToken(token, refresh); err != nil {
+		if revokeErr := s.revokePair(ctx, userID, pairID); revokeErr != nil {
+			slog.Error("failed to revoke refresh token on invalid token", "err", revokeErr)
+		}
+		return models.RefreshToken{}, err
 	}
 
 	return token, nil
 }
+
+// lookupRefreshToken finds the stored row of a presented refresh token without
+// checking its secret part. Legacy tokens are located through the access
+// token pair when it is known and through their lookup hash otherwise.
+func (s Service) lookupRefreshToken(ctx context.Context, userID, pairID, refresh string) (models.RefreshToken, error) {
+	if selector, _, ok := auth.SplitRefreshToken(refresh); ok {
+		return s.repo.FindRefreshTokenBySelector(ctx, selector)
+	}
+
+	if pairID != "" {
+		return s.repo.FindRefreshTokenByPairID(ctx, userID, pairID)
+	}
+
+	lookup, err := auth.RefreshTokenLookup(refresh)
+	if err != nil {
+		return models.RefreshToken{}, apperrors.ErrTokenIsNotFound
+	}
+	return s.repo.FindRefreshTokenByLookup(ctx, lookup)
+}
+
+func (s Service) verifyRefreshToken(token models.RefreshToken, refresh string) error {
+	if token.VerifierHash != "" {
+		_, verifier, ok := auth.SplitRefreshToken(refresh)
+		if !ok || !auth.VerifyRefreshVerifier([]byte(s.cfg.JWT.RefreshHashKey), verifier, token.VerifierHash) {
+			return fmt.Errorf("refresh token verifier mismatch: %w", apperrors.ErrInvalidToken)
+		}
+		return nil
+	}
+
+	if token.TokenHash == "" {
+		return fmt.Errorf("refresh token has no stored hash: %w", apperrors.ErrInvalidToken)
+	}
+
+	if until := s.cfg.JWT.LegacyRefreshUntil; !until.IsZero() && time.Now().After(until) {
+		return fmt.Errorf("legacy refresh tokens are no longer accepted: %w", apperrors.ErrInvalidToken)
+	}
+
+	if err := auth.VerifyLegacyRefreshToken(refresh, token.TokenHash); err != nil {
+		return fmt.Errorf("refresh token hash mismatch: %w", apperrors.ErrInvalidToken)
+	}
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"auth-service/internal/apperrors"
+	"auth-service/internal/webauthn"
+	"auth-service/models"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gookit/slog"
+	"strings"
+	"time"
+)
+
+func (s Service) relyingParty() webauthn.Config {
+	return webauthn.Config{
+		RPID:    s.cfg.WebAuthn.RPID,
+		RPName:  s.cfg.WebAuthn.RPName,
+		Origins: s.cfg.WebAuthn.Origins,
+	}
+}
+
+func (s Service) BeginWebAuthnRegistration(ctx context.Context, userID string) (models.WebAuthnRegisterBeginResponse, error) {
+	user, err := s.repo.FindUserByID(ctx, userID)
+	if err != nil {
+		return models.WebAuthnRegisterBeginResponse{}, fmt.Errorf("find user: %w", err)
+	}
+
+	credentials, err := s.repo.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return models.WebAuthnRegisterBeginResponse{}, fmt.Errorf("list webauthn credentials: %w", err)
+	}
+
+	sessionID, challenge, err := s.newWebAuthnChallenge(ctx, userID, models.WebAuthnCeremonyRegistration)
+	if err != nil {
+		return models.WebAuthnRegisterBeginResponse{}, err
+	}
+
+	userHandle, err := uuid.Parse(user.ID)
+	if err != nil {
+		return models.WebAuthnRegisterBeginResponse{}, fmt.Errorf("parse user id: %w", err)
+	}
+
+	return models.WebAuthnRegisterBeginResponse{
+		SessionID: sessionID,
+		PublicKey: webauthn.CreationOptions{
+			Challenge: challenge,
+			RP:        webauthn.RelyingParty{ID: s.cfg.WebAuthn.RPID, Name: s.cfg.WebAuthn.RPName},
+			User: webauthn.UserEntity{
+				ID:          userHandle[:],
+				Name:        user.Email,
+				DisplayName: user.Email,
+			},
+			PubKeyCredParams:   webauthn.CredentialParameters(),
+			Timeout:            s.cfg.WebAuthn.ChallengeTTL.Milliseconds(),
+			Attestation:        "none",
+			ExcludeCredentials: credentialDescriptors(credentials),
+			AuthenticatorSelection: webauthn.AuthenticatorSelection{
+				ResidentKey:      "preferred",
+				UserVerification: "preferred",
+			},
+		},
+	}, nil
+}
+
+func (s Service) FinishWebAuthnRegistration(ctx context.Context, userID string, req models.WebAuthnRegisterFinishRequest, ip, userAgent string) (models.WebAuthnCredentialResponse, error) {
+	challenge, err := s.repo.ConsumeWebAuthnChallenge(ctx, req.SessionID, models.WebAuthnCeremonyRegistration)
+	if err != nil {
+		return models.WebAuthnCredentialResponse{}, fmt.Errorf("consume webauthn challenge: %w", err)
+	}
+	if challenge.UserID != userID {
+		return models.WebAuthnCredentialResponse{}, apperrors.ErrChallengeNotFound
+	}
+
+	verified, err := s.relyingParty().VerifyRegistration(challenge.Challenge, req.Credential)
+	if err != nil {
+		slog.Warn("webauthn registration rejected", "user_id", userID, "err", err)
+		return models.WebAuthnCredentialResponse{}, fmt.Errorf("%w: %v", apperrors.ErrInvalidCredential, err)
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = userAgent
+	}
+
+	credential := models.WebAuthnCredential{
+		ID:        verified.ID,
+		UserID:    userID,
+		PublicKey: verified.PublicKey,
+		Alg:       verified.Alg,
+		SignCount: verified.SignCount,
+		AAGUID:    verified.AAGUID,
+		Name:      name,
+	}
+	event := models.AuditEvent{
+		UserID:    userID,
+		Type:      models.AuditWebAuthnRegistered,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"credential_id": base64.RawURLEncoding.EncodeToString(verified.ID),
+			"alg":           verified.Alg,
+		},
+	}
+
+	credential, err = s.repo.SaveWebAuthnCredential(ctx, credential, event)
+	if err != nil {
+		return models.WebAuthnCredentialResponse{}, fmt.Errorf("save webauthn credential: %w", err)
+	}
+
+	return models.WebAuthnCredentialResponse{
+		ID:        credential.ID,
+		Name:      credential.Name,
+		CreatedAt: credential.CreatedAt,
+	}, nil
+}
+
+// BeginWebAuthnLogin starts an assertion. The browser always offers the
+// discoverable credentials (passkeys) it holds for the relying party: listing
+// the credentials of the email in allowCredentials would tell anyone whether
+// the account exists and has passkeys. A known email only restricts the
+// challenge to the credentials of its user.
+func (s Service) BeginWebAuthnLogin(ctx context.Context, email string) (models.WebAuthnLoginBeginResponse, error) {
+	var userID string
+
+	if email = strings.TrimSpace(email); email != "" {
+		user, err := s.repo.FindUserByEmail(ctx, email)
+		switch {
+		case err == nil:
+			userID = user.ID
+		case !errors.Is(err, apperrors.ErrUserNotFound):
+			return models.WebAuthnLoginBeginResponse{}, fmt.Errorf("find user: %w", err)
+		}
+	}
+
+	sessionID, challenge, err := s.newWebAuthnChallenge(ctx, userID, models.WebAuthnCeremonyAuthentication)
+	if err != nil {
+		return models.WebAuthnLoginBeginResponse{}, err
+	}
+
+	return models.WebAuthnLoginBeginResponse{
+		SessionID: sessionID,
+		PublicKey: webauthn.RequestOptions{
+			Challenge:        challenge,
+			RPID:             s.cfg.WebAuthn.RPID,
+			Timeout:          s.cfg.WebAuthn.ChallengeTTL.Milliseconds(),
+			UserVerification: "preferred",
+		},
+	}, nil
+}
+
+func (s Service) FinishWebAuthnLogin(ctx context.Context, req models.WebAuthnLoginFinishRequest, ip, userAgent string) (models.TokensResponse, error) {
+	challenge, err := s.repo.ConsumeWebAuthnChallenge(ctx, req.SessionID, models.WebAuthnCeremonyAuthentication)
+	if err != nil {
+		return models.TokensResponse{}, fmt.Errorf("consume webauthn challenge: %w", err)
+	}
+
+	credential, err := s.repo.FindWebAuthnCredential(ctx, req.Credential.RawID)
+	if err != nil {
+		return models.TokensResponse{}, fmt.Errorf("find webauthn credential: %w", err)
+	}
+	if credential.Cloned {
+		return models.TokensResponse{}, apperrors.ErrCredentialCloned
+	}
+	if challenge.UserID != "" && challenge.UserID != credential.UserID {
+		return models.TokensResponse{}, apperrors.ErrCredentialNotFound
+	}
+
+	if handle := req.Credential.Response.UserHandle; len(handle) > 0 {
+		owner, err := uuid.Parse(credential.UserID)
+		if err != nil || !bytes.Equal(handle, owner[:]) {
+			return models.TokensResponse{}, fmt.Errorf("user handle does not match the credential owner: %w", apperrors.ErrInvalidCredential)
+		}
+	}
+
+	authData, err := s.relyingParty().VerifyAssertion(challenge.Challenge, req.Credential, webauthn.Credential{
+		ID:        credential.ID,
+		PublicKey: credential.PublicKey,
+		Alg:       credential.Alg,
+		SignCount: credential.SignCount,
+	})
+	if errors.Is(err, webauthn.ErrSignCount) {
+		s.disableClonedCredential(ctx, credential, authData.SignCount, ip, userAgent)
+		return models.TokensResponse{}, apperrors.ErrCredentialCloned
+	}
+	if err != nil {
+		slog.Warn("webauthn assertion rejected", "user_id", credential.UserID, "err", err)
+		return models.TokensResponse{}, fmt.Errorf("%w: %v", apperrors.ErrInvalidCredential, err)
+	}
+
+	if err = s.repo.UpdateWebAuthnSignCount(ctx, credential.ID, credential.SignCount, authData.SignCount); err != nil {
+		if errors.Is(err, apperrors.ErrCredentialCloned) {
+			s.disableClonedCredential(ctx, credential, authData.SignCount, ip, userAgent)
+		}
+		return models.TokensResponse{}, fmt.Errorf("update sign count: %w", err)
+	}
+
+	amr := []string{models.AMRHardwareKey}
+	if authData.UserVerified() {
+		amr = append(amr, models.AMRMFA)
+	}
+
+	return s.GenerateTokens(ctx, models.Grant{
+		UserID:    credential.UserID,
+		IP:        ip,
+		UserAgent: userAgent,
+		AMR:       amr,
+	})
+}
+
+// disableClonedCredential is called when an authenticator reports a signature
+// counter that is not above the stored one: two copies of the private key are
+// in use, so the credential can no longer prove possession.
+func (s Service) disableClonedCredential(ctx context.Context, credential models.WebAuthnCredential, reported uint32, ip, userAgent string) {
+	credentialID := base64.RawURLEncoding.EncodeToString(credential.ID)
+
+	slog.Warn("webauthn clone detected, credential disabled",
+		"user_id", credential.UserID, "credential_id", credentialID,
+		"stored_sign_count", credential.SignCount, "reported_sign_count", reported)
+
+	event := models.AuditEvent{
+		UserID:    credential.UserID,
+		Type:      models.AuditWebAuthnCloneDetected,
+		IP:        ip,
+		UserAgent: userAgent,
+		Details: map[string]interface{}{
+			"credential_id":       credentialID,
+			"stored_sign_count":   credential.SignCount,
+			"reported_sign_count": reported,
+		},
+	}
+	if err := s.repo.MarkWebAuthnCredentialCloned(ctx, credential.ID, event); err != nil {
+		slog.Error("failed to disable cloned webauthn credential", "credential_id", credentialID, "err", err)
+	}
+}
+
+func (s Service) newWebAuthnChallenge(ctx context.Context, userID, ceremony string) (string, []byte, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", nil, err
+	}
+
+	sessionID, err := s.repo.SaveWebAuthnChallenge(ctx, models.WebAuthnChallenge{
+		UserID:    userID,
+		Ceremony:  ceremony,
+		Challenge: challenge,
+		ExpiresAt: time.Now().Add(s.cfg.WebAuthn.ChallengeTTL),
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("save webauthn challenge: %w", err)
+	}
+
+	return sessionID, challenge, nil
+}
+
+func credentialDescriptors(credentials []models.WebAuthnCredential) []webauthn.CredentialDescriptor {
+	descriptors := make([]webauthn.CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		if credential.Cloned {
+			continue
+		}
+		descriptors = append(descriptors, webauthn.CredentialDescriptor{Type: "public-key", ID: credential.ID})
+	}
+	return descriptors
+}
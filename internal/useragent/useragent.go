@@ -0,0 +1,139 @@
+package useragent
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+const UnknownBrowser = "Unknown browser"
+
+type UserAgent struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	Device         string
+}
+
+type browserRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// Order matters: Chromium based browsers also announce Chrome and Safari.
+var browserRules = []browserRule{
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/([\d.]+)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/([\d.]+)`)},
+	{"Yandex Browser", regexp.MustCompile(`YaBrowser/([\d.]+)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/([\d.]+)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/([\d.]+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/([\d.]+)`)},
+	{"Safari", regexp.MustCompile(`Version/([\d.]+).*Safari/`)},
+	{"curl", regexp.MustCompile(`^curl/([\d.]+)`)},
+	{"Postman", regexp.MustCompile(`PostmanRuntime/([\d.]+)`)},
+	{"Go HTTP client", regexp.MustCompile(`^Go-http-client/([\d.]+)`)},
+}
+
+var (
+	botPattern     = regexp.MustCompile(`(?i)bot|crawler|spider|slurp`)
+	windowsPattern = regexp.MustCompile(`Windows NT ([\d.]+)`)
+	androidPattern = regexp.MustCompile(`Android ([\d.]+)`)
+	iosPattern     = regexp.MustCompile(`(?:iPhone|CPU) OS ([\d_]+)`)
+	macPattern     = regexp.MustCompile(`Mac OS X ([\d_.]+)`)
+)
+
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+}
+
+func Parse(ua string) UserAgent {
+	result := UserAgent{
+		Browser: UnknownBrowser,
+		OS:      "Unknown OS",
+		Device:  DeviceUnknown,
+	}
+
+	for _, rule := range browserRules {
+		if match := rule.pattern.FindStringSubmatch(ua); match != nil {
+			result.Browser = rule.name
+			result.BrowserVersion = majorVersion(match[1])
+			break
+		}
+	}
+
+	result.OS = parseOS(ua)
+	result.Device = parseDevice(ua, result.OS)
+
+	return result
+}
+
+// Family identifies the browser independently of its version and of the OS
+// version, so it stays the same across auto-updates: Safari on iOS is only
+// updated together with the OS.
+func (u UserAgent) Family() string {
+	os, _, _ := strings.Cut(u.OS, " ")
+	return u.Browser + "/" + os
+}
+
+func (u UserAgent) String() string {
+	browser := u.Browser
+	if u.BrowserVersion != "" {
+		browser += " " + u.BrowserVersion
+	}
+	return browser + " on " + u.OS
+}
+
+func parseOS(ua string) string {
+	switch {
+	case windowsPattern.MatchString(ua):
+		version := windowsPattern.FindStringSubmatch(ua)[1]
+		if name, ok := windowsVersions[version]; ok {
+			return "Windows " + name
+		}
+		return "Windows"
+	case androidPattern.MatchString(ua):
+		return "Android " + majorVersion(androidPattern.FindStringSubmatch(ua)[1])
+	case strings.Contains(ua, "iPad"):
+		return "iPadOS"
+	case iosPattern.MatchString(ua):
+		return "iOS " + majorVersion(strings.ReplaceAll(iosPattern.FindStringSubmatch(ua)[1], "_", "."))
+	case macPattern.MatchString(ua):
+		return "macOS"
+	case strings.Contains(ua, "CrOS"):
+		return "ChromeOS"
+	case strings.Contains(ua, "Linux"):
+		return "Linux"
+	default:
+		return "Unknown OS"
+	}
+}
+
+func parseDevice(ua, os string) string {
+	switch {
+	case botPattern.MatchString(ua):
+		return DeviceBot
+	case strings.Contains(ua, "iPad") || (strings.HasPrefix(os, "Android") && !strings.Contains(ua, "Mobile")):
+		return DeviceTablet
+	case strings.Contains(ua, "Mobi") || strings.HasPrefix(os, "iOS") || strings.HasPrefix(os, "Android"):
+		return DeviceMobile
+	case strings.HasPrefix(os, "Windows") || os == "macOS" || os == "Linux" || os == "ChromeOS":
+		return DeviceDesktop
+	default:
+		return DeviceUnknown
+	}
+}
+
+func majorVersion(version string) string {
+	major, _, _ := strings.Cut(version, ".")
+	return major
+}
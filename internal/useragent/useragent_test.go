@@ -0,0 +1,84 @@
+package useragent
+
+import "testing"
+
+const (
+	chromeWindows124 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.91 Safari/537.36"
+	chromeWindows125 = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.6422.60 Safari/537.36"
+	chromeAndroid124 = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.6367.82 Mobile Safari/537.36"
+	chromeAndroid125 = "Mozilla/5.0 (Linux; Android 15; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.6422.53 Mobile Safari/537.36"
+	chromeIOS        = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/124.0.6367.88 Mobile/15E148 Safari/604.1"
+	firefoxLinux125  = "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"
+	firefoxLinux126  = "Mozilla/5.0 (X11; Linux x86_64; rv:126.0) Gecko/20100101 Firefox/126.0"
+	firefoxMac       = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14.4; rv:125.0) Gecko/20100101 Firefox/125.0"
+	safariMac17      = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Safari/605.1.15"
+	safariMac18      = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Safari/605.1.15"
+	safariIPhone17   = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Mobile/15E148 Safari/604.1"
+	safariIPhone18   = "Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.0 Mobile/15E148 Safari/604.1"
+	safariIPad       = "Mozilla/5.0 (iPad; CPU OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+	edgeWindows124   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.80"
+	edgeWindows125   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36 Edg/125.0.2535.51"
+	edgeAndroid      = "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36 EdgA/124.0.2478.64"
+	googlebot        = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	curlClient       = "curl/8.7.1"
+	unknownUserAgent = "custom-client"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want UserAgent
+	}{
+		{"Chrome on Windows", chromeWindows124, UserAgent{"Chrome", "124", "Windows 10", DeviceDesktop}},
+		{"Chrome on Android", chromeAndroid124, UserAgent{"Chrome", "124", "Android 14", DeviceMobile}},
+		{"Chrome on iOS", chromeIOS, UserAgent{"Chrome", "124", "iOS 17", DeviceMobile}},
+		{"Firefox on Linux", firefoxLinux125, UserAgent{"Firefox", "125", "Linux", DeviceDesktop}},
+		{"Firefox on macOS", firefoxMac, UserAgent{"Firefox", "125", "macOS", DeviceDesktop}},
+		{"Safari on macOS", safariMac17, UserAgent{"Safari", "17", "macOS", DeviceDesktop}},
+		{"Safari on iPhone", safariIPhone17, UserAgent{"Safari", "17", "iOS 17", DeviceMobile}},
+		{"Safari on iPad", safariIPad, UserAgent{"Safari", "17", "iPadOS", DeviceTablet}},
+		{"Edge on Windows", edgeWindows124, UserAgent{"Edge", "124", "Windows 10", DeviceDesktop}},
+		{"Edge on Android", edgeAndroid, UserAgent{"Edge", "124", "Android 10", DeviceMobile}},
+		{"bot", googlebot, UserAgent{UnknownBrowser, "", "Unknown OS", DeviceBot}},
+		{"curl", curlClient, UserAgent{"curl", "8", "Unknown OS", DeviceUnknown}},
+		{"unknown", unknownUserAgent, UserAgent{UnknownBrowser, "", "Unknown OS", DeviceUnknown}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.ua); got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFamily(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		same   bool
+	}{
+		{"Chrome version bump", chromeWindows124, chromeWindows125, true},
+		{"Chrome and Android version bump", chromeAndroid124, chromeAndroid125, true},
+		{"Firefox version bump", firefoxLinux125, firefoxLinux126, true},
+		{"Safari version bump", safariMac17, safariMac18, true},
+		{"Safari updated with iOS", safariIPhone17, safariIPhone18, true},
+		{"Edge version bump", edgeWindows124, edgeWindows125, true},
+		{"Edge is not Chrome", chromeWindows124, edgeWindows124, false},
+		{"Chrome is not Safari", chromeIOS, safariIPhone17, false},
+		{"same browser on another OS", firefoxLinux125, firefoxMac, false},
+		{"iPhone is not iPad", safariIPhone17, safariIPad, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after := Parse(tt.before).Family(), Parse(tt.after).Family()
+			if (before == after) != tt.same {
+				t.Errorf("Family() = %q and %q, same = %v, want %v", before, after, before == after, tt.same)
+			}
+		})
+	}
+}
@@ -1,15 +1,15 @@
 package utils
 
 import (
-	"auth-service/config"
 	"auth-service/models"
+	"auth-service/pkg/webhooksig"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/gookit/slog"
-	"net"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 )
 
 func SendJSON(w http.ResponseWriter, statusCode int, data interface{}) {
@@ -31,43 +31,31 @@ func WriteError(w http.ResponseWriter, statusCode int, message string) {
 	json.NewEncoder(w).Encode(models.Error{Error: message})
 }
 
-func GetIP(r *http.Request) string {
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		parts := strings.Split(forwarded, ",")
-		return strings.TrimSpace(parts[0])
-	}
-
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		return r.RemoteAddr
-	}
-
-	return ip
-}
-
-func NotifyNewIP(userID, oldIP, newIP, userAgent string) {
-	webhookURL := config.GetConfig().Webhook.URL
-	if webhookURL == "" {
-		return
-	}
-	payload := map[string]interface{}{
-		"user_id":    userID,
-		"old_ip":     oldIP,
-		"new_ip":     newIP,
-		"user_agent": userAgent,
-	}
-	err := SendWebhook(webhookURL, payload)
-	if err != nil {
-		slog.Error("failed to send webhook", "err", err)
+// WriteOAuthError writes an error response of RFC 6749 section 5.2.
+func WriteOAuthError(w http.ResponseWriter, statusCode int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if statusCode == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
 	}
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(models.OAuthError{Error: code, ErrorDescription: description})
 }
 
-func SendWebhook(url string, payload interface{}) error {
-	data, err := json.Marshal(payload)
+// SendWebhook posts the payload of an outbox event to url, signed with every
+// secret as pkg/webhooksig describes. The delivery is bounded by ctx.
+func SendWebhook(ctx context.Context, url string, event models.OutboxEvent, secrets [][]byte) error {
+	body := []byte(event.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhooksig.IDHeader, strconv.FormatInt(event.ID, 10))
+	req.Header.Set(webhooksig.EventHeader, event.Type)
+	webhooksig.SignRequest(req, secrets, body, time.Now())
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
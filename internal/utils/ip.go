@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+)
+
+// IPResolver finds the address of the client behind a chain of reverse
+// proxies. Forwarding headers are only believed when the request comes from
+// a trusted proxy, and the chain they describe is walked from the right: the
+// first hop that is not a trusted proxy is the client. Everything to the left
+// of it could have been written by the client itself.
+//
+// Only the one header the proxies of the deployment set is read: a proxy
+// appending to X-Forwarded-For passes a Forwarded header written by the
+// client through untouched.
+type IPResolver struct {
+	trusted []netip.Prefix
+	header  string
+}
+
+// NewIPResolver accepts CIDRs and bare addresses of the trusted proxies and
+// the header they set: Forwarded, X-Forwarded-For or X-Real-IP.
+func NewIPResolver(trustedProxies []string, header string) (*IPResolver, error) {
+	header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+	switch header {
+	case "Forwarded", "X-Forwarded-For", "X-Real-Ip":
+	default:
+		return nil, fmt.Errorf("unsupported client ip header %q", header)
+	}
+
+	resolver := &IPResolver{header: header}
+	for _, value := range trustedProxies {
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("parse trusted proxy %q: %w", value, err)
+		}
+		resolver.trusted = append(resolver.trusted, prefix)
+	}
+	return resolver, nil
+}
+
+// ClientIP returns the client address of the request taken from the header
+// of the resolver, the other forwarding headers are ignored.
+func (res *IPResolver) ClientIP(r *http.Request) string {
+	remote, ok := parseHostPort(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !res.isTrusted(remote) {
+		return remote.String()
+	}
+
+	var hops []string
+	switch res.header {
+	case "Forwarded":
+		hops = forwardedFor(r.Header.Values("Forwarded"))
+	case "X-Forwarded-For":
+		hops = splitHeaderList(r.Header.Values("X-Forwarded-For"))
+	case "X-Real-Ip":
+		if value := strings.TrimSpace(r.Header.Get("X-Real-IP")); value != "" {
+			hops = []string{value}
+		}
+	}
+
+	client := remote
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHostPort(hops[i])
+		if !ok {
+			// An unknown or obfuscated hop: nothing to its left can be
+			// attributed, the last proxy seen is the best answer.
+			break
+		}
+		client = addr
+		if !res.isTrusted(addr) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+func (res *IPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range res.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+var defaultResolver atomic.Pointer[IPResolver]
+
+func init() {
+	defaultResolver.Store(&IPResolver{})
+}
+
+// SetTrustedProxies configures the resolver used by GetIP. Until it is
+// called no proxy is trusted and forwarding headers are ignored.
+func SetTrustedProxies(trustedProxies []string, header string) error {
+	resolver, err := NewIPResolver(trustedProxies, header)
+	if err != nil {
+		return err
+	}
+	defaultResolver.Store(resolver)
+	return nil
+}
+
+func GetIP(r *http.Request) string {
+	return defaultResolver.Load().ClientIP(r)
+}
+
+// forwardedFor extracts the for= parameters of RFC 7239 Forwarded headers,
+// e.g. `for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8::1]:4711"`.
+func forwardedFor(headers []string) []string {
+	var hops []string
+	for _, element := range splitHeaderList(headers) {
+		node := "unknown"
+		for _, pair := range strings.Split(element, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(name, "for") {
+				node = strings.Trim(value, `"`)
+			}
+		}
+		hops = append(hops, node)
+	}
+	return hops
+}
+
+func splitHeaderList(headers []string) []string {
+	var items []string
+	for _, header := range headers {
+		for _, item := range strings.Split(header, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+	}
+	return items
+}
+
+// parseHostPort parses an address with an optional port, IPv6 addresses
+// with a port are bracketed. IPv4-mapped IPv6 addresses are unmapped.
+func parseHostPort(value string) (netip.Addr, bool) {
+	host := value
+	if h, _, err := net.SplitHostPort(value); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+func parsePrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
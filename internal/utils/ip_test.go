@@ -0,0 +1,246 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPResolverClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.168.1.1", "2001:db8:ffff::/48"}
+
+	tests := []struct {
+		name string
+		// header is the TRUSTED_PROXY_HEADER, X-Forwarded-For when empty.
+		header  string
+		remote  string
+		headers map[string][]string
+		want    string
+	}{
+		{
+			name:   "direct client",
+			remote: "203.0.113.7:51234",
+			want:   "203.0.113.7",
+		},
+		{
+			name:    "spoofed X-Forwarded-For from an untrusted peer",
+			remote:  "203.0.113.7:51234",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "spoofed X-Real-IP from an untrusted peer",
+			header:  "X-Real-IP",
+			remote:  "203.0.113.7:51234",
+			headers: map[string][]string{"X-Real-Ip": {"1.2.3.4"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "spoofed Forwarded from an untrusted peer",
+			header:  "Forwarded",
+			remote:  "203.0.113.7:51234",
+			headers: map[string][]string{"Forwarded": {"for=1.2.3.4"}},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "single trusted proxy",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.20"}},
+			want:    "198.51.100.20",
+		},
+		{
+			name:    "client prepends a fake hop",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.20"}},
+			want:    "198.51.100.20",
+		},
+		{
+			name:    "chain of trusted proxies",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.20, 192.168.1.1, 10.1.2.3"}},
+			want:    "198.51.100.20",
+		},
+		{
+			name:    "untrusted hop in the middle stops the walk",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.20, 203.0.113.9, 10.1.2.3"}},
+			want:    "203.0.113.9",
+		},
+		{
+			name:    "multiple X-Forwarded-For headers are one list",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4", "198.51.100.20, 10.1.2.3"}},
+			want:    "198.51.100.20",
+		},
+		{
+			name:    "every hop trusted",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Forwarded-For": {"10.9.9.9, 10.1.2.3"}},
+			want:    "10.9.9.9",
+		},
+		{
+			name:    "garbage hop",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.20, not-an-ip"}},
+			want:    "10.0.0.5",
+		},
+		{
+			name:    "garbage left of the client is ignored",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Forwarded-For": {"<script>, 198.51.100.20"}},
+			want:    "198.51.100.20",
+		},
+		{
+			name:    "X-Forwarded-For with a port",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.20:8443"}},
+			want:    "198.51.100.20",
+		},
+		{
+			name:    "X-Real-IP from a trusted proxy",
+			header:  "X-Real-IP",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Real-Ip": {"198.51.100.20"}},
+			want:    "198.51.100.20",
+		},
+		{
+			name:    "X-Real-IP is ignored behind X-Forwarded-For proxies",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"X-Real-Ip": {"1.2.3.4"}},
+			want:    "10.0.0.5",
+		},
+		{
+			name:   "X-Forwarded-For is ignored behind X-Real-IP proxies",
+			header: "X-Real-IP",
+			remote: "10.0.0.5:443",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"1.2.3.4"},
+				"X-Real-Ip":       {"198.51.100.20"},
+			},
+			want: "198.51.100.20",
+		},
+		{
+			name:    "Forwarded",
+			header:  "Forwarded",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"Forwarded": {"for=198.51.100.20;proto=https;by=10.0.0.5"}},
+			want:    "198.51.100.20",
+		},
+		{
+			name:    "Forwarded with a quoted IPv6 address and port",
+			header:  "Forwarded",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"Forwarded": {`for="[2001:db8:cafe::17]:4711"`}},
+			want:    "2001:db8:cafe::17",
+		},
+		{
+			name:    "Forwarded chain with a fake first element",
+			header:  "Forwarded",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"Forwarded": {"for=1.2.3.4, For=198.51.100.20, for=10.1.2.3"}},
+			want:    "198.51.100.20",
+		},
+		{
+			name:    "Forwarded with an obfuscated node",
+			header:  "Forwarded",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"Forwarded": {"for=_hidden, for=10.1.2.3"}},
+			want:    "10.1.2.3",
+		},
+		{
+			name:    "Forwarded element without for",
+			header:  "Forwarded",
+			remote:  "10.0.0.5:443",
+			headers: map[string][]string{"Forwarded": {"proto=https"}},
+			want:    "10.0.0.5",
+		},
+		{
+			name:   "client-sent Forwarded passed through an X-Forwarded-For proxy",
+			remote: "10.0.0.5:443",
+			headers: map[string][]string{
+				"Forwarded":       {"for=1.2.3.4"},
+				"X-Forwarded-For": {"198.51.100.20"},
+			},
+			want: "198.51.100.20",
+		},
+		{
+			name:   "client-sent X-Forwarded-For passed through a Forwarded proxy",
+			header: "Forwarded",
+			remote: "10.0.0.5:443",
+			headers: map[string][]string{
+				"Forwarded":       {"for=198.51.100.20"},
+				"X-Forwarded-For": {"1.2.3.4"},
+			},
+			want: "198.51.100.20",
+		},
+		{
+			name:    "trusted IPv6 proxy",
+			remote:  "[2001:db8:ffff::1]:443",
+			headers: map[string][]string{"X-Forwarded-For": {"2001:db8:1::5"}},
+			want:    "2001:db8:1::5",
+		},
+		{
+			name:    "IPv4-mapped IPv6 peer matches an IPv4 CIDR",
+			remote:  "[::ffff:10.0.0.5]:443",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.20"}},
+			want:    "198.51.100.20",
+		},
+		{
+			name:    "proxy outside a bare trusted address",
+			remote:  "192.168.1.2:443",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.20"}},
+			want:    "192.168.1.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == "" {
+				header = "X-Forwarded-For"
+			}
+			resolver, err := NewIPResolver(trusted, header)
+			if err != nil {
+				t.Fatalf("new resolver: %v", err)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/token/refresh", nil)
+			r.RemoteAddr = tt.remote
+			for name, values := range tt.headers {
+				for _, value := range values {
+					r.Header.Add(name, value)
+				}
+			}
+
+			if got := resolver.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetIPTrustsNoProxyByDefault(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/token/refresh", nil)
+	r.RemoteAddr = "10.0.0.5:443"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	if got := GetIP(r); got != "10.0.0.5" {
+		t.Errorf("GetIP() = %q, want the TCP peer", got)
+	}
+}
+
+func TestNewIPResolverRejectsInvalidCIDR(t *testing.T) {
+	for _, value := range []string{"10.0.0.0/33", "proxy.internal", ""} {
+		if _, err := NewIPResolver([]string{value}, "X-Forwarded-For"); err == nil {
+			t.Errorf("NewIPResolver(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestNewIPResolverRejectsUnknownHeader(t *testing.T) {
+	for _, header := range []string{"", "X-Client-IP", "X-Forwarded-For, Forwarded"} {
+		if _, err := NewIPResolver(nil, header); err == nil {
+			t.Errorf("NewIPResolver(header %q) succeeded, want an error", header)
+		}
+	}
+}
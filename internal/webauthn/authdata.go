@@ -0,0 +1,82 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Authenticator data flags.
+const (
+	FlagUserPresent   byte = 0x01
+	FlagUserVerified  byte = 0x04
+	FlagAttestedData  byte = 0x40
+	FlagExtensionData byte = 0x80
+)
+
+const (
+	authDataMinLength     = 37
+	attestedDataMinLength = 18
+)
+
+type AuthenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func (d AuthenticatorData) UserVerified() bool {
+	return d.Flags&FlagUserVerified != 0
+}
+
+// ParseAuthenticatorData decodes the structure described in section 6.1 of
+// the WebAuthn specification. The credential public key is kept as raw COSE.
+func ParseAuthenticatorData(data []byte) (AuthenticatorData, error) {
+	if len(data) < authDataMinLength {
+		return AuthenticatorData{}, fmt.Errorf("authenticator data is too short")
+	}
+
+	result := AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[authDataMinLength:]
+
+	if result.Flags&FlagAttestedData != 0 {
+		if len(rest) < attestedDataMinLength {
+			return AuthenticatorData{}, fmt.Errorf("attested credential data is too short")
+		}
+		result.AAGUID = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[attestedDataMinLength:]
+		if len(rest) < idLength {
+			return AuthenticatorData{}, fmt.Errorf("credential id is truncated")
+		}
+		result.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return AuthenticatorData{}, fmt.Errorf("decode credential public key: %w", err)
+		}
+		result.PublicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if result.Flags&FlagExtensionData != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return AuthenticatorData{}, fmt.Errorf("decode extensions: %w", err)
+		}
+		rest = after
+	}
+
+	if len(rest) != 0 {
+		return AuthenticatorData{}, fmt.Errorf("trailing bytes in authenticator data")
+	}
+
+	return result, nil
+}
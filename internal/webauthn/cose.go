@@ -0,0 +1,143 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers offered to authenticators, in order of preference.
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+	AlgRS256 int64 = -257
+)
+
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters of RFC 9053.
+const (
+	coseKeyType    = 1
+	coseAlg        = 3
+	coseCurve      = -1
+	coseX          = -2
+	coseY          = -3
+	coseRSAModulus = -1
+	coseRSAExp     = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+const minRSABits = 2048
+
+type PublicKey struct {
+	Alg int64
+	Key crypto.PublicKey
+}
+
+// ParsePublicKey decodes a COSE_Key as stored in the attested credential data.
+func ParsePublicKey(coseKey []byte) (PublicKey, error) {
+	decoded, rest, err := decodeCBOR(coseKey)
+	if err != nil {
+		return PublicKey{}, fmt.Errorf("decode cose key: %w", err)
+	}
+	if len(rest) != 0 {
+		return PublicKey{}, fmt.Errorf("trailing bytes after cose key")
+	}
+
+	params, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return PublicKey{}, fmt.Errorf("cose key is not a map")
+	}
+
+	kty, _ := params[int64(coseKeyType)].(int64)
+	alg, _ := params[int64(coseAlg)].(int64)
+
+	switch {
+	case alg == AlgES256 && kty == coseKeyTypeEC2:
+		return parseES256Key(params)
+	case alg == AlgEdDSA && kty == coseKeyTypeOKP:
+		return parseEdDSAKey(params)
+	case alg == AlgRS256 && kty == coseKeyTypeRSA:
+		return parseRS256Key(params)
+	default:
+		return PublicKey{}, fmt.Errorf("unsupported cose key type %d with algorithm %d", kty, alg)
+	}
+}
+
+func parseES256Key(params map[interface{}]interface{}) (PublicKey, error) {
+	crv, _ := params[int64(coseCurve)].(int64)
+	x, _ := params[int64(coseX)].([]byte)
+	y, _ := params[int64(coseY)].([]byte)
+	if crv != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+		return PublicKey{}, fmt.Errorf("invalid P-256 cose key")
+	}
+
+	// ecdh rejects points that are not on the curve.
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+		return PublicKey{}, fmt.Errorf("invalid P-256 point: %w", err)
+	}
+
+	return PublicKey{
+		Alg: AlgES256,
+		Key: &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)},
+	}, nil
+}
+
+func parseEdDSAKey(params map[interface{}]interface{}) (PublicKey, error) {
+	crv, _ := params[int64(coseCurve)].(int64)
+	x, _ := params[int64(coseX)].([]byte)
+	if crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+		return PublicKey{}, fmt.Errorf("invalid Ed25519 cose key")
+	}
+
+	return PublicKey{Alg: AlgEdDSA, Key: ed25519.PublicKey(x)}, nil
+}
+
+func parseRS256Key(params map[interface{}]interface{}) (PublicKey, error) {
+	n, _ := params[int64(coseRSAModulus)].([]byte)
+	e, _ := params[int64(coseRSAExp)].([]byte)
+	if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+		return PublicKey{}, fmt.Errorf("invalid RSA cose key")
+	}
+
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if key.N.BitLen() < minRSABits {
+		return PublicKey{}, fmt.Errorf("RSA key shorter than %d bits", minRSABits)
+	}
+
+	return PublicKey{Alg: AlgRS256, Key: key}, nil
+}
+
+func (k PublicKey) Verify(data, signature []byte) error {
+	digest := sha256.Sum256(data)
+
+	var ok bool
+	switch key := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", k.Key)
+	}
+
+	if !ok {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+// Package webauthn implements the relying party side of the WebAuthn
+// registration and authentication ceremonies. Only the "none" attestation
+// format is accepted: the service trusts the key, not the authenticator model.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+	challengeSize  = 32
+)
+
+var (
+	ErrInvalidSignature = errors.New("webauthn: invalid signature")
+	// ErrSignCount means the authenticator reported a signature counter that
+	// did not grow, which indicates a cloned authenticator.
+	ErrSignCount = errors.New("webauthn: signature counter did not increase")
+)
+
+type Config struct {
+	RPID    string
+	RPName  string
+	Origins []string
+}
+
+// Base64URL is a byte string carried in JSON as unpadded base64url, the way
+// browsers serialize ArrayBuffers of a PublicKeyCredential.
+type Base64URL []byte
+
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return fmt.Errorf("decode base64url: %w", err)
+	}
+	*b = decoded
+	return nil
+}
+
+type RelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string    `json:"type"`
+	ID   Base64URL `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions is passed to navigator.credentials.create as publicKey.
+type CreationOptions struct {
+	Challenge              Base64URL              `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	Attestation            string                 `json:"attestation"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+}
+
+// RequestOptions is passed to navigator.credentials.get as publicKey.
+type RequestOptions struct {
+	Challenge        Base64URL              `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+type AttestationResponse struct {
+	ID       string    `json:"id"`
+	RawID    Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AttestationObject Base64URL `json:"attestationObject"`
+	} `json:"response"`
+}
+
+type AssertionResponse struct {
+	ID       string    `json:"id"`
+	RawID    Base64URL `json:"rawId"`
+	Type     string    `json:"type"`
+	Response struct {
+		ClientDataJSON    Base64URL `json:"clientDataJSON"`
+		AuthenticatorData Base64URL `json:"authenticatorData"`
+		Signature         Base64URL `json:"signature"`
+		UserHandle        Base64URL `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+// Credential is what the relying party keeps after a registration.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	Alg       int64
+	SignCount uint32
+	AAGUID    []byte
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("generate webauthn challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+func CredentialParameters() []CredentialParameter {
+	params := make([]CredentialParameter, len(SupportedAlgorithms))
+	for i, alg := range SupportedAlgorithms {
+		params[i] = CredentialParameter{Type: "public-key", Alg: alg}
+	}
+	return params
+}
+
+// VerifyRegistration checks an attestation produced for challenge and returns
+// the new credential.
+func (c Config) VerifyRegistration(challenge []byte, resp AttestationResponse) (Credential, error) {
+	if err := c.verifyClientData(resp.Response.ClientDataJSON, ceremonyCreate, challenge); err != nil {
+		return Credential{}, err
+	}
+
+	decoded, rest, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, fmt.Errorf("decode attestation object: %w", err)
+	}
+	object, ok := decoded.(map[interface{}]interface{})
+	if !ok || len(rest) != 0 {
+		return Credential{}, fmt.Errorf("malformed attestation object")
+	}
+
+	format, _ := object["fmt"].(string)
+	statement, _ := object["attStmt"].(map[interface{}]interface{})
+	if format != "none" || len(statement) != 0 {
+		return Credential{}, fmt.Errorf("unsupported attestation format %q", format)
+	}
+
+	rawAuthData, _ := object["authData"].([]byte)
+	authData, err := ParseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if err = c.verifyAuthenticatorData(authData); err != nil {
+		return Credential{}, err
+	}
+	if authData.Flags&FlagAttestedData == 0 {
+		return Credential{}, fmt.Errorf("attested credential data is missing")
+	}
+	if len(resp.RawID) > 0 && !bytes.Equal(resp.RawID, authData.CredentialID) {
+		return Credential{}, fmt.Errorf("credential id does not match attested credential data")
+	}
+
+	key, err := ParsePublicKey(authData.PublicKey)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{
+		ID:        authData.CredentialID,
+		PublicKey: authData.PublicKey,
+		Alg:       key.Alg,
+		SignCount: authData.SignCount,
+		AAGUID:    authData.AAGUID,
+	}, nil
+}
+
+// VerifyAssertion checks an assertion produced for challenge by credential and
+// returns the parsed authenticator data. ErrSignCount is returned together with
+// the data when the signature is valid but the counter went backwards.
+func (c Config) VerifyAssertion(challenge []byte, resp AssertionResponse, credential Credential) (AuthenticatorData, error) {
+	if err := c.verifyClientData(resp.Response.ClientDataJSON, ceremonyGet, challenge); err != nil {
+		return AuthenticatorData{}, err
+	}
+
+	authData, err := ParseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return AuthenticatorData{}, err
+	}
+	if err = c.verifyAuthenticatorData(authData); err != nil {
+		return AuthenticatorData{}, err
+	}
+
+	key, err := ParsePublicKey(credential.PublicKey)
+	if err != nil {
+		return AuthenticatorData{}, err
+	}
+
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(slices.Clip(resp.Response.AuthenticatorData), clientDataHash[:]...)
+	if err = key.Verify(signed, resp.Response.Signature); err != nil {
+		return AuthenticatorData{}, err
+	}
+
+	// Authenticators that do not implement a counter always report zero.
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		return authData, ErrSignCount
+	}
+
+	return authData, nil
+}
+
+func (c Config) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("decode client data: %w", err)
+	}
+
+	if data.Type != ceremony {
+		return fmt.Errorf("unexpected client data type %q", data.Type)
+	}
+
+	received, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return fmt.Errorf("challenge mismatch")
+	}
+
+	if !slices.Contains(c.Origins, data.Origin) {
+		return fmt.Errorf("origin %q is not allowed", data.Origin)
+	}
+
+	return nil
+}
+
+func (c Config) verifyAuthenticatorData(data AuthenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if subtle.ConstantTimeCompare(data.RPIDHash, rpIDHash[:]) != 1 {
+		return fmt.Errorf("rp id hash mismatch")
+	}
+
+	if data.Flags&FlagUserPresent == 0 {
+		return fmt.Errorf("user presence flag is not set")
+	}
+
+	return nil
+}
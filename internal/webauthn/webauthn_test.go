@@ -0,0 +1,385 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const testOrigin = "https://auth.example.com"
+
+var testConfig = Config{
+	RPID:    "auth.example.com",
+	RPName:  "Example",
+	Origins: []string{testOrigin},
+}
+
+// softAuthenticator is a software implementation of an authenticator that
+// produces "none" attestations and assertions the way a browser would.
+type softAuthenticator struct {
+	t          *testing.T
+	alg        int64
+	signer     crypto.Signer
+	id         []byte
+	signCount  uint32
+	noCounter  bool
+	noPresence bool
+	rpID       string
+	origin     string
+}
+
+func newSoftAuthenticator(t *testing.T, alg int64) *softAuthenticator {
+	t.Helper()
+
+	var signer crypto.Signer
+	var err error
+	switch alg {
+	case AlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	case AlgRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return &softAuthenticator{
+		t:      t,
+		alg:    alg,
+		signer: signer,
+		id:     id,
+		rpID:   testConfig.RPID,
+		origin: testOrigin,
+	}
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	switch key := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		x, y := make([]byte, 32), make([]byte, 32)
+		key.X.FillBytes(x)
+		key.Y.FillBytes(y)
+		return encodeCBOR(map[interface{}]interface{}{
+			coseKeyType: coseKeyTypeEC2, coseAlg: AlgES256, coseCurve: coseCurveP256, coseX: x, coseY: y,
+		})
+	case ed25519.PublicKey:
+		return encodeCBOR(map[interface{}]interface{}{
+			coseKeyType: coseKeyTypeOKP, coseAlg: AlgEdDSA, coseCurve: coseCurveEd25519, coseX: []byte(key),
+		})
+	case *rsa.PublicKey:
+		e := make([]byte, 4)
+		binary.BigEndian.PutUint32(e, uint32(key.E))
+		return encodeCBOR(map[interface{}]interface{}{
+			coseKeyType: coseKeyTypeRSA, coseAlg: AlgRS256, coseRSAModulus: key.N.Bytes(), coseRSAExp: e[1:],
+		})
+	}
+	a.t.Fatalf("unsupported key type")
+	return nil
+}
+
+func (a *softAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(ceremony string, challenge []byte) []byte {
+	data, _ := json.Marshal(map[string]interface{}{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    a.origin,
+	})
+	return data
+}
+
+func (a *softAuthenticator) register(challenge []byte) AttestationResponse {
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.id)))
+	attested = append(attested, a.id...)
+	attested = append(attested, a.coseKey()...)
+
+	var resp AttestationResponse
+	resp.ID = base64.RawURLEncoding.EncodeToString(a.id)
+	resp.RawID = a.id
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = a.clientData(ceremonyCreate, challenge)
+	resp.Response.AttestationObject = encodeCBOR(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": a.authData(FlagUserPresent|FlagUserVerified|FlagAttestedData, attested),
+	})
+	return resp
+}
+
+func (a *softAuthenticator) assert(challenge []byte) AssertionResponse {
+	if !a.noCounter {
+		a.signCount++
+	}
+
+	flags := FlagUserPresent | FlagUserVerified
+	if a.noPresence {
+		flags = FlagUserVerified
+	}
+
+	var resp AssertionResponse
+	resp.ID = base64.RawURLEncoding.EncodeToString(a.id)
+	resp.RawID = a.id
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = a.clientData(ceremonyGet, challenge)
+	resp.Response.AuthenticatorData = a.authData(flags, nil)
+
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), resp.Response.AuthenticatorData...), clientDataHash[:]...)
+
+	var signature []byte
+	var err error
+	if a.alg == AlgEdDSA {
+		signature, err = a.signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(signed)
+		signature, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		a.t.Fatalf("sign assertion: %v", err)
+	}
+	resp.Response.Signature = signature
+
+	return resp
+}
+
+func mustChallenge(t *testing.T) []byte {
+	t.Helper()
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatalf("new challenge: %v", err)
+	}
+	return challenge
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	algs := map[string]int64{"ES256": AlgES256, "EdDSA": AlgEdDSA, "RS256": AlgRS256}
+
+	for name, alg := range algs {
+		t.Run(name, func(t *testing.T) {
+			authenticator := newSoftAuthenticator(t, alg)
+
+			challenge := mustChallenge(t)
+			credential, err := testConfig.VerifyRegistration(challenge, authenticator.register(challenge))
+			if err != nil {
+				t.Fatalf("VerifyRegistration: %v", err)
+			}
+			if credential.Alg != alg {
+				t.Fatalf("credential alg = %d, want %d", credential.Alg, alg)
+			}
+
+			for i := 0; i < 3; i++ {
+				challenge = mustChallenge(t)
+				authData, err := testConfig.VerifyAssertion(challenge, authenticator.assert(challenge), credential)
+				if err != nil {
+					t.Fatalf("VerifyAssertion #%d: %v", i, err)
+				}
+				if !authData.UserVerified() {
+					t.Fatalf("user verified flag is not set")
+				}
+				credential.SignCount = authData.SignCount
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionRejectsTamperedInput(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+	challenge := mustChallenge(t)
+	credential, err := testConfig.VerifyRegistration(challenge, authenticator.register(challenge))
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(a *softAuthenticator, challenge []byte) AssertionResponse
+	}{
+		{"other challenge", func(a *softAuthenticator, _ []byte) AssertionResponse {
+			return a.assert(mustChallenge(t))
+		}},
+		{"foreign origin", func(a *softAuthenticator, challenge []byte) AssertionResponse {
+			a.origin = "https://evil.example.com"
+			return a.assert(challenge)
+		}},
+		{"foreign rp id", func(a *softAuthenticator, challenge []byte) AssertionResponse {
+			a.rpID = "evil.example.com"
+			return a.assert(challenge)
+		}},
+		{"registration client data", func(a *softAuthenticator, challenge []byte) AssertionResponse {
+			resp := a.assert(challenge)
+			resp.Response.ClientDataJSON = a.clientData(ceremonyCreate, challenge)
+			return resp
+		}},
+		{"modified signature", func(a *softAuthenticator, challenge []byte) AssertionResponse {
+			resp := a.assert(challenge)
+			resp.Response.Signature[len(resp.Response.Signature)-1] ^= 0xff
+			return resp
+		}},
+		{"user not present", func(a *softAuthenticator, challenge []byte) AssertionResponse {
+			a.noPresence = true
+			return a.assert(challenge)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := *authenticator
+			challenge := mustChallenge(t)
+			if _, err := testConfig.VerifyAssertion(challenge, tt.modify(&a, challenge), credential); err == nil {
+				t.Fatal("VerifyAssertion succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionDetectsClonedAuthenticator(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+	challenge := mustChallenge(t)
+	credential, err := testConfig.VerifyRegistration(challenge, authenticator.register(challenge))
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+
+	clone := *authenticator
+
+	challenge = mustChallenge(t)
+	authData, err := testConfig.VerifyAssertion(challenge, authenticator.assert(challenge), credential)
+	if err != nil {
+		t.Fatalf("VerifyAssertion: %v", err)
+	}
+	credential.SignCount = authData.SignCount
+
+	challenge = mustChallenge(t)
+	if _, err = testConfig.VerifyAssertion(challenge, clone.assert(challenge), credential); !errors.Is(err, ErrSignCount) {
+		t.Fatalf("VerifyAssertion with cloned authenticator = %v, want ErrSignCount", err)
+	}
+}
+
+func TestVerifyAssertionAcceptsZeroCounter(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgEdDSA)
+	authenticator.noCounter = true
+	challenge := mustChallenge(t)
+	credential, err := testConfig.VerifyRegistration(challenge, authenticator.register(challenge))
+	if err != nil {
+		t.Fatalf("VerifyRegistration: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		challenge = mustChallenge(t)
+		if _, err = testConfig.VerifyAssertion(challenge, authenticator.assert(challenge), credential); err != nil {
+			t.Fatalf("VerifyAssertion #%d: %v", i, err)
+		}
+	}
+}
+
+func TestVerifyRegistrationRejectsAttestationStatement(t *testing.T) {
+	authenticator := newSoftAuthenticator(t, AlgES256)
+	challenge := mustChallenge(t)
+	resp := authenticator.register(challenge)
+
+	decoded, _, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil {
+		t.Fatalf("decode attestation object: %v", err)
+	}
+	object := decoded.(map[interface{}]interface{})
+	object["fmt"] = "packed"
+	object["attStmt"] = map[interface{}]interface{}{"alg": AlgES256, "sig": []byte{1}}
+	resp.Response.AttestationObject = encodeCBOR(object)
+
+	if _, err = testConfig.VerifyRegistration(challenge, resp); err == nil {
+		t.Fatal("VerifyRegistration accepted a packed attestation")
+	}
+}
+
+func TestDecodeCBORRejectsMalformedInput(t *testing.T) {
+	nested := make([]byte, maxCBORDepth+2)
+	for i := range nested {
+		nested[i] = 0x81
+	}
+
+	tests := map[string][]byte{
+		"empty":              {},
+		"truncated string":   {0x45, 1, 2},
+		"indefinite array":   {0x9f, 0x01, 0xff},
+		"huge array":         {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"array map key":      {0xa1, 0x80, 0x01},
+		"duplicate map key":  {0xa2, 0x01, 0x01, 0x01, 0x02},
+		"too deeply nested":  nested,
+		"integer overflow":   {0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"unsupported simple": {0xf8, 0x20},
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(data); err == nil {
+				t.Fatal("decodeCBOR succeeded, want error")
+			}
+		})
+	}
+}
+
+// encodeCBOR is the encoding counterpart used by the software authenticator.
+func encodeCBOR(value interface{}) []byte {
+	switch v := value.(type) {
+	case int:
+		return encodeCBOR(int64(v))
+	case int64:
+		if v < 0 {
+			return cborHeader(1, uint64(-1-v))
+		}
+		return cborHeader(0, uint64(v))
+	case []byte:
+		return append(cborHeader(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHeader(3, uint64(len(v))), v...)
+	case []interface{}:
+		out := cborHeader(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case map[interface{}]interface{}:
+		out := cborHeader(5, uint64(len(v)))
+		for key, item := range v {
+			out = append(out, encodeCBOR(key)...)
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	}
+	panic("encodeCBOR: unsupported type")
+}
+
+func cborHeader(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(arg))
+	case arg <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(arg))
+	default:
+		return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, arg)
+	}
+}
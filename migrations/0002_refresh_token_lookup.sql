@@ -0,0 +1,2 @@
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS token_lookup CHAR(64);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_lookup ON refresh_tokens (token_lookup);
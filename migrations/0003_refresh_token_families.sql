@@ -0,0 +1,19 @@
+-- Tokens issued before families existed each start a family of their own.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id UUID;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES refresh_tokens (id);
+UPDATE refresh_tokens SET family_id = gen_random_uuid() WHERE family_id IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_parent_id ON refresh_tokens (parent_id);
+
+CREATE TABLE IF NOT EXISTS audit_events
+(
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    UUID,
+    event_type TEXT      NOT NULL,
+    ip         TEXT      NOT NULL DEFAULT '',
+    user_agent TEXT      NOT NULL DEFAULT '',
+    details    JSONB     NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events (user_id, created_at);
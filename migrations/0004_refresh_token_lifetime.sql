@@ -0,0 +1,8 @@
+-- Tokens issued before lifetimes existed count from their creation and get
+-- the default JWT_REFRESH_TTL of 720h.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMPTZ;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+UPDATE refresh_tokens SET auth_time = created_at WHERE auth_time IS NULL;
+UPDATE refresh_tokens SET expires_at = created_at + INTERVAL '720 hours' WHERE expires_at IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN auth_time SET NOT NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN expires_at SET NOT NULL;
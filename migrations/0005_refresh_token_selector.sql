@@ -0,0 +1,6 @@
+-- New tokens are stored as selector and verifier_hash. Existing bcrypt tokens
+-- keep token_hash and are accepted until REFRESH_LEGACY_ACCEPT_UNTIL.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS selector VARCHAR(32);
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS verifier_hash CHAR(64);
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash DROP NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_selector ON refresh_tokens (selector);
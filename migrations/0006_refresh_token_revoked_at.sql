@@ -0,0 +1,5 @@
+-- The revocation time of older revoked tokens is unknown. Treating them as
+-- revoked now keeps their access tokens denied until they expire.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+UPDATE refresh_tokens SET revoked_at = NOW() WHERE revoked AND revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_revoked_at ON refresh_tokens (revoked_at) WHERE revoked_at IS NOT NULL;
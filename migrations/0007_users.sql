@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS users
+(
+    id            UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    email         TEXT        NOT NULL,
+    password_hash TEXT        NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (lower(email));
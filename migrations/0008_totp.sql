@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id        UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret         TEXT        NOT NULL,
+    confirmed_at   TIMESTAMPTZ,
+    last_used_step BIGINT      NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  CHAR(64)    NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recovery_codes_user_code ON recovery_codes (user_id, code_hash);
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS amr TEXT[] NOT NULL DEFAULT '{}';
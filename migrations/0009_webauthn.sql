@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS webauthn_credentials
+(
+    id           BYTEA PRIMARY KEY,
+    user_id      UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    public_key   BYTEA       NOT NULL,
+    alg          INTEGER     NOT NULL,
+    sign_count   BIGINT      NOT NULL DEFAULT 0,
+    aaguid       BYTEA,
+    name         TEXT        NOT NULL DEFAULT '',
+    cloned       BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_webauthn_credentials_user_id ON webauthn_credentials (user_id);
+
+CREATE TABLE IF NOT EXISTS webauthn_challenges
+(
+    id         UUID PRIMARY KEY     DEFAULT gen_random_uuid(),
+    user_id    UUID,
+    ceremony   TEXT        NOT NULL,
+    challenge  BYTEA       NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_webauthn_challenges_expires_at ON webauthn_challenges (expires_at);
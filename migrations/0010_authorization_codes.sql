@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS authorization_codes
+(
+    code_hash      CHAR(64) PRIMARY KEY,
+    client_id      TEXT        NOT NULL,
+    user_id        UUID        NOT NULL,
+    redirect_uri   TEXT        NOT NULL,
+    code_challenge TEXT        NOT NULL,
+    scope          TEXT        NOT NULL DEFAULT '',
+    amr            TEXT[]      NOT NULL DEFAULT '{}',
+    auth_time      TIMESTAMPTZ NOT NULL,
+    expires_at     TIMESTAMPTZ NOT NULL,
+    used_at        TIMESTAMPTZ,
+    family_id      UUID,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_authorization_codes_expires_at ON authorization_codes (expires_at);
+
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS client_id TEXT;
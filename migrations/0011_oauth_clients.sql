@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS oauth_clients
+(
+    id                TEXT PRIMARY KEY,
+    name              TEXT        NOT NULL DEFAULT '',
+    secret_hash       TEXT,
+    redirect_uris     TEXT[]      NOT NULL DEFAULT '{}',
+    grant_types       TEXT[]      NOT NULL DEFAULT '{}',
+    scopes            TEXT[]      NOT NULL DEFAULT '{}',
+    access_token_ttl  INTEGER,
+    refresh_token_ttl INTEGER,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Codes live for a minute; those of clients that are not registered yet can
+-- not be redeemed anyway.
+DELETE FROM authorization_codes WHERE client_id NOT IN (SELECT id FROM oauth_clients);
+ALTER TABLE authorization_codes
+    DROP CONSTRAINT IF EXISTS authorization_codes_client_id_fkey,
+    ADD CONSTRAINT authorization_codes_client_id_fkey
+        FOREIGN KEY (client_id) REFERENCES oauth_clients (id) ON DELETE CASCADE;
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS permissions
+(
+    name        TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS roles
+(
+    name        TEXT PRIMARY KEY,
+    description TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role_name  TEXT NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    permission TEXT NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+    PRIMARY KEY (role_name, permission)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles
+(
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_name  TEXT        NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role_name)
+);
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_name ON user_roles (role_name);
+
+INSERT INTO permissions (name, description)
+VALUES ('roles:read', 'View roles and their permissions'),
+       ('roles:write', 'Create, change and delete roles and assign them to users')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles (name, description)
+VALUES ('admin', 'Full access to the admin API'),
+       ('support', 'Read-only access for the support staff')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission)
+VALUES ('admin', 'roles:read'),
+       ('admin', 'roles:write'),
+       ('support', 'roles:read')
+ON CONFLICT DO NOTHING;
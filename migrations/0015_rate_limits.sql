@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS rate_limits
+(
+    key          TEXT PRIMARY KEY,
+    tokens       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at   TIMESTAMPTZ,
+    locked_until TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limits_updated_at ON rate_limits (updated_at);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS outbox
+(
+    id              BIGSERIAL PRIMARY KEY,
+    event_type      TEXT        NOT NULL,
+    payload         JSONB       NOT NULL,
+    status          TEXT        NOT NULL DEFAULT 'pending',
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_outbox_status ON outbox (status, id);
+
+INSERT INTO permissions (name, description)
+VALUES ('outbox:read', 'View webhook deliveries'),
+       ('outbox:write', 'Replay dead-lettered webhook deliveries')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_name, permission)
+VALUES ('admin', 'outbox:read'),
+       ('admin', 'outbox:write'),
+       ('support', 'outbox:read')
+ON CONFLICT DO NOTHING;
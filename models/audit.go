@@ -0,0 +1,29 @@
+package models
+
+const (
+	AuditRefreshTokenReuse      = "refresh_token_reuse"
+	AuditSessionRevoked         = "session_revoked"
+	AuditLogoutAll              = "logout_all"
+	AuditMFAEnabled             = "mfa_enabled"
+	AuditRecoveryCodeUsed       = "recovery_code_used"
+	AuditWebAuthnRegistered     = "webauthn_registered"
+	AuditWebAuthnCloneDetected  = "webauthn_clone_detected"
+	AuditAuthorizationCodeReuse = "authorization_code_reuse"
+	AuditRoleCreated            = "role_created"
+	AuditRoleUpdated            = "role_updated"
+	AuditRoleDeleted            = "role_deleted"
+	AuditRoleAssigned           = "role_assigned"
+	AuditRoleUnassigned         = "role_unassigned"
+	AuditBindingMismatch        = "binding_mismatch"
+	AuditRefreshLockout         = "refresh_lockout"
+	AuditMFALockout             = "mfa_lockout"
+	AuditOutboxReplayed         = "outbox_replayed"
+)
+
+type AuditEvent struct {
+	UserID    string
+	Type      string
+	IP        string
+	UserAgent string
+	Details   map[string]interface{}
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+const (
+	GrantAuthorizationCode = "authorization_code"
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+)
+
+type OAuthClient struct {
+	ID              string
+	Name            string
+	SecretHash      string
+	RedirectURIs    []string
+	GrantTypes      []string
+	Scopes          []string
+	Audiences       []string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	CreatedAt       time.Time
+}
+
+// Confidential clients authenticate with a secret, public ones (browser and
+// mobile apps) rely on PKCE alone.
+func (c OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+func (c OAuthClient) AllowsGrant(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Authentication method references of RFC 8176 placed in the amr claim.
+const (
+	AMRPassword    = "pwd"
+	AMROTP         = "otp"
+	AMRMFA         = "mfa"
+	AMRHardwareKey = "hwk"
+)
+
+type TOTP struct {
+	UserID       string
+	Secret       string
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+}
+
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TOTPConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFALoginRequest struct {
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Delivery states of outbox events. Dead events ran out of attempts and wait
+// for a replay through the admin API.
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxDead      = "dead"
+)
+
+// Types of the events sent to WEBHOOK_URL.
+const (
+	WebhookNewIP             = "new_ip"
+	WebhookNewUserAgent      = "new_user_agent"
+	WebhookRefreshTokenReuse = "refresh_token_reuse"
+)
+
+type OutboxEvent struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+}
+
+type OutboxEventsResponse struct {
+	Events []OutboxEvent `json:"events"`
+}
+
+type OutboxReplayResponse struct {
+	Replayed int64 `json:"replayed"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RateLimitBucket is the state of a token bucket. A zero UpdatedAt means the
+// bucket was never used and is full.
+type RateLimitBucket struct {
+	Key         string
+	Tokens      float64
+	UpdatedAt   time.Time
+	LockedUntil time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Permissions checked by the admin API. The set is fixed by the migrations,
+// roles only group them.
+const (
+	PermissionRolesRead   = "roles:read"
+	PermissionRolesWrite  = "roles:write"
+	PermissionOutboxRead  = "outbox:read"
+	PermissionOutboxWrite = "outbox:write"
+)
+
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type RoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type RolesResponse struct {
+	Roles []Role `json:"roles"`
+}
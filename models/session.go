@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type Session struct {
+	PairID     string    `json:"pair_id"`
+	Device     string    `json:"device"`
+	Browser    string    `json:"browser"`
+	OS         string    `json:"os"`
+	DeviceType string    `json:"device_type"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type LogoutAllRequest struct {
+	KeepCurrent bool `json:"keep_current"`
+}
+
+type LogoutAllResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+type SessionsResponse struct {
+	Sessions   []Session `json:"sessions"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
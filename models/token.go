@@ -7,6 +7,16 @@ import (
 type TokensResponse struct {
 	Access  string `json:"access"`
 	Refresh string `json:"refresh"`
+	IDToken string `json:"id_token,omitempty"`
+}
+
+// LoginResponse holds either a token pair or, when the account has a second
+// factor, the mfa_pending token to present to /login/mfa.
+type LoginResponse struct {
+	Access      string `json:"access,omitempty"`
+	Refresh     string `json:"refresh,omitempty"`
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
 }
 
 type RefreshRequest struct {
@@ -16,12 +26,69 @@ type RefreshRequest struct {
 }
 
 type RefreshToken struct {
-	ID          int
-	UserID      string
-	TokenHash   string
-	TokenPairID string
-	UserAgent   string
-	IP          string
-	Revoked     bool
-	CreatedAt   time.Time
+	ID           int
+	UserID       string
+	Selector     string
+	VerifierHash string
+	TokenHash    string
+	TokenLookup  string
+	TokenPairID  string
+	FamilyID     string
+	ParentID     *int
+	UserAgent    string
+	IP           string
+	AMR          []string
+	ClientID     string
+	Scope        string
+	Revoked      bool
+	Rotated      bool
+	AuthTime     time.Time
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+// RevokedPair is a revoked token pair and the time its access token expires
+// at the latest, given the access token lifetime of its client.
+type RevokedPair struct {
+	PairID          string
+	RevokedAt       time.Time
+	AccessExpiresAt time.Time
+}
+
+type RevocationEvent struct {
+	PairIDs   []string  `json:"pair_ids"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type IntrospectionResponse struct {
+	Active      bool     `json:"active"`
+	TokenType   string   `json:"token_type,omitempty"`
+	Sub         string   `json:"sub,omitempty"`
+	Exp         int64    `json:"exp,omitempty"`
+	Iat         int64    `json:"iat,omitempty"`
+	TokenPairID string   `json:"token_pair_id,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	Aud         []string `json:"aud,omitempty"`
+}
+
+// Grant describes the authentication a token pair is issued for.
+type Grant struct {
+	UserID    string
+	IP        string
+	UserAgent string
+	AMR       []string
+	AuthTime  time.Time
+	ClientID  string
+	// Scope is granted to the client; with "openid" an ID token carrying
+	// Nonce is issued along with the pair.
+	Scope string
+	Nonce string
+	// Roles are embedded in the access token as the roles claim.
+	Roles []string
+	// Audience is the aud of the access token, JWT_AUDIENCE when empty.
+	Audience []string
+	// AccessTTL and RefreshTTL override the configured lifetimes when set.
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
 }
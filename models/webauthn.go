@@ -0,0 +1,63 @@
+package models
+
+import (
+	"auth-service/internal/webauthn"
+	"time"
+)
+
+const (
+	WebAuthnCeremonyRegistration   = "registration"
+	WebAuthnCeremonyAuthentication = "authentication"
+)
+
+type WebAuthnCredential struct {
+	ID         []byte
+	UserID     string
+	PublicKey  []byte
+	Alg        int64
+	SignCount  uint32
+	AAGUID     []byte
+	Name       string
+	Cloned     bool
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+type WebAuthnChallenge struct {
+	ID        string
+	UserID    string
+	Ceremony  string
+	Challenge []byte
+	ExpiresAt time.Time
+}
+
+type WebAuthnRegisterBeginResponse struct {
+	SessionID string                   `json:"session_id"`
+	PublicKey webauthn.CreationOptions `json:"publicKey"`
+}
+
+type WebAuthnRegisterFinishRequest struct {
+	SessionID  string                       `json:"session_id"`
+	Name       string                       `json:"name,omitempty"`
+	Credential webauthn.AttestationResponse `json:"credential"`
+}
+
+type WebAuthnCredentialResponse struct {
+	ID        webauthn.Base64URL `json:"id"`
+	Name      string             `json:"name"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type WebAuthnLoginBeginRequest struct {
+	Email string `json:"email,omitempty"`
+}
+
+type WebAuthnLoginBeginResponse struct {
+	SessionID string                  `json:"session_id"`
+	PublicKey webauthn.RequestOptions `json:"publicKey"`
+}
+
+type WebAuthnLoginFinishRequest struct {
+	SessionID  string                     `json:"session_id"`
+	Credential webauthn.AssertionResponse `json:"credential"`
+}
@@ -0,0 +1,157 @@
+// Package webhooksig signs and verifies the webhooks of auth-service.
+//
+// Every delivery carries the Unix time of sending in X-Timestamp and, in
+// X-Signature, one "sha256=<hex>" entry per active signing secret, each an
+// HMAC-SHA256 of "<timestamp>.<body>". The X-Webhook-Id header is not signed,
+// so deduplication by it does not stop replays; the timestamp tolerance does.
+// A receiver accepts the delivery when any entry matches any of its secrets,
+// so secrets can be rotated without downtime: add the new secret to the
+// service, then to the receivers, then remove the old one from the service.
+//
+//	verifier := webhooksig.NewVerifier(os.Getenv("WEBHOOK_SECRET"))
+//
+//	func handle(w http.ResponseWriter, r *http.Request) {
+//		body, err := verifier.VerifyRequest(r)
+//		if err != nil {
+//			http.Error(w, "invalid signature", http.StatusUnauthorized)
+//			return
+//		}
+//		...
+//	}
+package webhooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Timestamp"
+	// IDHeader carries the id of the event, the same on every retry and
+	// replay, so receivers can drop duplicates. EventHeader carries its type.
+	IDHeader    = "X-Webhook-Id"
+	EventHeader = "X-Webhook-Event"
+
+	// DefaultTolerance is how far the timestamp of a delivery may be from the
+	// clock of the receiver. It bounds the window for replaying a captured
+	// delivery.
+	DefaultTolerance = 5 * time.Minute
+
+	// MaxBodySize bounds the body read by VerifyRequest.
+	MaxBodySize = 1 << 20
+
+	scheme = "sha256="
+)
+
+var (
+	ErrMissingSignature = errors.New("webhooksig: missing signature or timestamp header")
+	ErrInvalidTimestamp = errors.New("webhooksig: invalid timestamp")
+	ErrExpired          = errors.New("webhooksig: timestamp outside the tolerance")
+	ErrInvalidSignature = errors.New("webhooksig: no matching signature")
+)
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" under secret.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeaderValue signs body with every secret and joins the results
+// into the value of X-Signature.
+func SignatureHeaderValue(secrets [][]byte, timestamp int64, body []byte) string {
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		signatures = append(signatures, scheme+Sign(secret, timestamp, body))
+	}
+	return strings.Join(signatures, ",")
+}
+
+// SignRequest sets X-Timestamp and X-Signature of a delivery of body.
+func SignRequest(req *http.Request, secrets [][]byte, body []byte, now time.Time) {
+	timestamp := now.Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, SignatureHeaderValue(secrets, timestamp, body))
+}
+
+type Verifier struct {
+	secrets   [][]byte
+	Tolerance time.Duration
+	// Now is the clock of the receiver, time.Now when nil.
+	Now func() time.Time
+}
+
+// NewVerifier accepts deliveries signed with any of secrets.
+func NewVerifier(secrets ...string) *Verifier {
+	verifier := &Verifier{Tolerance: DefaultTolerance}
+	for _, secret := range secrets {
+		if secret != "" {
+			verifier.secrets = append(verifier.secrets, []byte(secret))
+		}
+	}
+	return verifier
+}
+
+// Verify checks the X-Timestamp and X-Signature values of a delivery of body.
+func (v *Verifier) Verify(timestamp, signature string, body []byte) error {
+	if timestamp == "" || signature == "" {
+		return ErrMissingSignature
+	}
+
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	if age := now().Sub(time.Unix(sent, 0)); age > v.Tolerance || age < -v.Tolerance {
+		return ErrExpired
+	}
+
+	for _, entry := range strings.Split(signature, ",") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(entry), scheme)
+		if !ok {
+			continue
+		}
+		received, err := hex.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		for _, secret := range v.secrets {
+			expected, _ := hex.DecodeString(Sign(secret, sent, body))
+			if hmac.Equal(received, expected) {
+				return nil
+			}
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// VerifyRequest reads the body of a delivery and verifies it. The body is
+// returned for the handler, as it can not be read from the request again.
+func (v *Verifier) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = v.Verify(r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
@@ -0,0 +1,145 @@
+package webhooksig
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedRequest(t *testing.T, secrets []string, body []byte, sent time.Time) *http.Request {
+	t.Helper()
+
+	keys := make([][]byte, 0, len(secrets))
+	for _, secret := range secrets {
+		keys = append(keys, []byte(secret))
+	}
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	SignRequest(req, keys, body, sent)
+	return req
+}
+
+func TestVerifyRequest(t *testing.T) {
+	body := []byte(`{"event":"new_ip","user_id":"7c9e6679-7425-40de-944b-e07fc1f90ae7"}`)
+	sent := time.Unix(1_760_000_000, 0)
+
+	tests := []struct {
+		name     string
+		signWith []string
+		verifier []string
+		now      time.Time
+		tamper   func(req *http.Request)
+		wantErr  error
+	}{
+		{
+			name:     "round trip",
+			signWith: []string{"secret"},
+			verifier: []string{"secret"},
+			now:      sent,
+		},
+		{
+			name:     "receiver knows only the new secret during rotation",
+			signWith: []string{"old", "new"},
+			verifier: []string{"new"},
+			now:      sent,
+		},
+		{
+			name:     "receiver still on the old secret during rotation",
+			signWith: []string{"old", "new"},
+			verifier: []string{"old", "unrelated"},
+			now:      sent,
+		},
+		{
+			name:     "unknown secret",
+			signWith: []string{"secret"},
+			verifier: []string{"other"},
+			now:      sent,
+			wantErr:  ErrInvalidSignature,
+		},
+		{
+			name:     "within the tolerance",
+			signWith: []string{"secret"},
+			verifier: []string{"secret"},
+			now:      sent.Add(DefaultTolerance),
+		},
+		{
+			name:     "older than the tolerance",
+			signWith: []string{"secret"},
+			verifier: []string{"secret"},
+			now:      sent.Add(DefaultTolerance + time.Second),
+			wantErr:  ErrExpired,
+		},
+		{
+			name:     "from the future beyond the tolerance",
+			signWith: []string{"secret"},
+			verifier: []string{"secret"},
+			now:      sent.Add(-DefaultTolerance - time.Second),
+			wantErr:  ErrExpired,
+		},
+		{
+			name:     "tampered body",
+			signWith: []string{"secret"},
+			verifier: []string{"secret"},
+			now:      sent,
+			tamper: func(req *http.Request) {
+				req.Body = io.NopCloser(bytes.NewReader(bytes.Replace(body, []byte("new_ip"), []byte("new_user_agent"), 1)))
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:     "tampered timestamp",
+			signWith: []string{"secret"},
+			verifier: []string{"secret"},
+			now:      sent,
+			tamper: func(req *http.Request) {
+				req.Header.Set(TimestampHeader, strconv.FormatInt(sent.Unix()+1, 10))
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:     "missing timestamp",
+			signWith: []string{"secret"},
+			verifier: []string{"secret"},
+			now:      sent,
+			tamper: func(req *http.Request) {
+				req.Header.Del(TimestampHeader)
+			},
+			wantErr: ErrMissingSignature,
+		},
+		{
+			name:     "invalid timestamp",
+			signWith: []string{"secret"},
+			verifier: []string{"secret"},
+			now:      sent,
+			tamper: func(req *http.Request) {
+				req.Header.Set(TimestampHeader, "yesterday")
+			},
+			wantErr: ErrInvalidTimestamp,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := signedRequest(t, tt.signWith, body, sent)
+			if tt.tamper != nil {
+				tt.tamper(req)
+			}
+
+			verifier := NewVerifier(tt.verifier...)
+			now := tt.now
+			verifier.Now = func() time.Time { return now }
+
+			got, err := verifier.VerifyRequest(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyRequest() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !bytes.Equal(got, body) {
+				t.Errorf("VerifyRequest() body = %s, want %s", got, body)
+			}
+		})
+	}
+}